@@ -0,0 +1,57 @@
+package smartcontract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func (s *SupplyChainContract) getSubmitterOrg(ctx contractapi.TransactionContextInterface) (string, error) {
+	if sc, ok := ctx.(*SupplyChainContext); ok && sc.submitter != "" {
+		return sc.submitter, nil
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get submitter MSP ID: %v", err)
+	}
+	return mspID, nil
+}
+
+func (s *SupplyChainContract) requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	return s.requireRole(ctx, "admin")
+}
+
+func (s *SupplyChainContract) requireRole(ctx contractapi.TransactionContextInterface, role string) error {
+	ok, err := s.hasRole(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("submitter is not authorized: %s role required", role)
+	}
+	return nil
+}
+
+// hasRole reports whether the submitter's certificate carries the role
+// attribute, or the on-ledger configuration assigns the role to the
+// submitter's organization.
+func (s *SupplyChainContract) hasRole(ctx contractapi.TransactionContextInterface, role string) (bool, error) {
+	attribute, found, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return false, fmt.Errorf("failed to read submitter role: %v", err)
+	}
+	if found && attribute == role {
+		return true, nil
+	}
+
+	mspID, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return false, err
+	}
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	return containsString(config.RoleAssignments[role], mspID), nil
+}
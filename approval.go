@@ -0,0 +1,350 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	approvalPolicyObjectType   = "ApprovalPolicy"
+	pendingOperationObjectType = "PendingOperation"
+)
+
+type ApprovalPolicy struct {
+	Operation string    `json:"operation"`
+	Category  string    `json:"category"`
+	Approvers []string  `json:"approvers"`
+	Threshold int       `json:"threshold"`
+	TTLHours  int       `json:"ttl_hours"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PendingOperation struct {
+	ID         string    `json:"id"`
+	Operation  string    `json:"operation"`
+	ProductID  string    `json:"product_id"`
+	Args       []string  `json:"args"`
+	ProposedBy string    `json:"proposed_by"`
+	Approvers  []string  `json:"approvers"`
+	Threshold  int       `json:"threshold"`
+	Approvals  []string  `json:"approvals"`
+	Rejections []string  `json:"rejections"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Operations that can be placed under an approval policy, with the number of
+// arguments each expects after the product ID.
+var approvableOperations = map[string]int{
+	"TransferOwnership": 1,
+	"Recall":            0,
+	"DeleteProduct":     0,
+}
+
+// SetApprovalPolicy requires threshold of the given organizations to approve
+// an operation on products in category. Approvers are MSP IDs of registered
+// participants, each listed once. An empty category applies to all products
+// without a more specific policy.
+func (s *SupplyChainContract) SetApprovalPolicy(ctx contractapi.TransactionContextInterface, operation, category string, approvers []string, threshold int, ttlHours int) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if _, ok := approvableOperations[operation]; !ok {
+		return fmt.Errorf("operation %s does not support approval policies", operation)
+	}
+	seen := make(map[string]bool)
+	for _, approver := range approvers {
+		if seen[approver] {
+			return fmt.Errorf("approver %s listed more than once", approver)
+		}
+		seen[approver] = true
+
+		if err := s.requireParticipantMSP(ctx, approver); err != nil {
+			return err
+		}
+	}
+	if threshold <= 0 || threshold > len(approvers) {
+		return fmt.Errorf("threshold must be between 1 and %d, got %d", len(approvers), threshold)
+	}
+	if ttlHours <= 0 {
+		return fmt.Errorf("ttl must be positive, got %d hours", ttlHours)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy := ApprovalPolicy{
+		Operation: operation,
+		Category:  category,
+		Approvers: approvers,
+		Threshold: threshold,
+		TTLHours:  ttlHours,
+		UpdatedAt: txTime,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(approvalPolicyObjectType, []string{operation, category})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	policyJSON, err := marshalState(policy)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(key, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put approval policy into ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) ProposeOperation(ctx contractapi.TransactionContextInterface, id, operation, productID string, args []string) error {
+	argCount, ok := approvableOperations[operation]
+	if !ok {
+		return fmt.Errorf("operation %s does not support approval policies", operation)
+	}
+	if len(args) != argCount {
+		return fmt.Errorf("operation %s expects %d arguments, got %d", operation, argCount, len(args))
+	}
+
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+	policy, err := s.approvalPolicyFor(ctx, operation, product.Category)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return fmt.Errorf("no approval policy covers %s on product %s", operation, productID)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(pendingOperationObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	opJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if opJSON != nil {
+		return fmt.Errorf("pending operation with ID %s already exists", id)
+	}
+
+	proposer, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	op := PendingOperation{
+		ID:         id,
+		Operation:  operation,
+		ProductID:  productID,
+		Args:       args,
+		ProposedBy: proposer,
+		Approvers:  policy.Approvers,
+		Threshold:  policy.Threshold,
+		Approvals:  []string{},
+		Rejections: []string{},
+		Status:     "Pending",
+		ExpiresAt:  txTime.Add(time.Duration(policy.TTLHours) * time.Hour),
+		CreatedAt:  txTime,
+		UpdatedAt:  txTime,
+	}
+
+	err = s.putPendingOperation(ctx, &op)
+	if err != nil {
+		return fmt.Errorf("failed to put pending operation into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// ApproveOperation records the submitter's approval and executes the
+// operation once the policy threshold is met. An expired operation is marked
+// Expired instead.
+func (s *SupplyChainContract) ApproveOperation(ctx contractapi.TransactionContextInterface, id string) (*PendingOperation, error) {
+	return s.voteOnOperation(ctx, id, true)
+}
+
+// RejectOperation records the submitter's rejection. The operation is
+// rejected once the threshold can no longer be reached.
+func (s *SupplyChainContract) RejectOperation(ctx contractapi.TransactionContextInterface, id string) (*PendingOperation, error) {
+	return s.voteOnOperation(ctx, id, false)
+}
+
+func (s *SupplyChainContract) QueryPendingOperation(ctx contractapi.TransactionContextInterface, id string) (*PendingOperation, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingOperationObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	opJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending operation from ledger: %v", err)
+	}
+	if opJSON == nil {
+		return nil, fmt.Errorf("the pending operation with ID %s does not exist", id)
+	}
+
+	var op PendingOperation
+	err = json.Unmarshal(opJSON, &op)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pending operation JSON: %v", err)
+	}
+
+	return &op, nil
+}
+
+func (s *SupplyChainContract) voteOnOperation(ctx contractapi.TransactionContextInterface, id string, approve bool) (*PendingOperation, error) {
+	op, err := s.QueryPendingOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if op.Status != "Pending" {
+		return nil, fmt.Errorf("operation %s is %s", id, op.Status)
+	}
+
+	voter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(op.Approvers, voter) {
+		return nil, fmt.Errorf("%s is not an approver for operation %s", voter, id)
+	}
+	if containsString(op.Approvals, voter) || containsString(op.Rejections, voter) {
+		return nil, fmt.Errorf("%s has already voted on operation %s", voter, id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case txTime.After(op.ExpiresAt):
+		op.Status = "Expired"
+	case approve:
+		op.Approvals = append(op.Approvals, voter)
+		if len(op.Approvals) >= op.Threshold {
+			if err := s.executeOperation(ctx, op); err != nil {
+				return nil, fmt.Errorf("failed to execute operation %s: %v", id, err)
+			}
+			op.Status = "Executed"
+		}
+	default:
+		op.Rejections = append(op.Rejections, voter)
+		if len(op.Approvers)-len(op.Rejections) < op.Threshold {
+			op.Status = "Rejected"
+		}
+	}
+	op.UpdatedAt = txTime
+
+	err = s.putPendingOperation(ctx, op)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update pending operation: %v", err)
+	}
+
+	return op, nil
+}
+
+func (s *SupplyChainContract) executeOperation(ctx contractapi.TransactionContextInterface, op *PendingOperation) error {
+	switch op.Operation {
+	case "TransferOwnership":
+		return s.transferOwnership(ctx, op.ProductID, op.Args[0])
+	case "Recall":
+		product, err := s.readProduct(ctx, op.ProductID)
+		if err != nil {
+			return err
+		}
+		txTime, err := s.getTxTime(ctx)
+		if err != nil {
+			return err
+		}
+		product.Status = "Recalled"
+		product.UpdatedAt = txTime
+		return s.putProduct(ctx, product)
+	case "DeleteProduct":
+		product, err := s.readProduct(ctx, op.ProductID)
+		if err != nil {
+			return err
+		}
+		return s.deleteProduct(ctx, product)
+	}
+	return fmt.Errorf("unsupported operation %s", op.Operation)
+}
+
+// requireNoApprovalPolicy rejects direct invocation of an operation that is
+// governed by an approval policy and must go through ProposeOperation.
+func (s *SupplyChainContract) requireNoApprovalPolicy(ctx contractapi.TransactionContextInterface, operation, productID string) error {
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	policy, err := s.approvalPolicyFor(ctx, operation, product.Category)
+	if err != nil {
+		return err
+	}
+	if policy != nil {
+		return fmt.Errorf("%s on product %s requires approval from %d of %v; use ProposeOperation", operation, productID, policy.Threshold, policy.Approvers)
+	}
+	return nil
+}
+
+func (s *SupplyChainContract) approvalPolicyFor(ctx contractapi.TransactionContextInterface, operation, category string) (*ApprovalPolicy, error) {
+	for _, c := range []string{category, ""} {
+		key, err := ctx.GetStub().CreateCompositeKey(approvalPolicyObjectType, []string{operation, c})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		policyJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read approval policy: %v", err)
+		}
+		if policyJSON == nil {
+			continue
+		}
+
+		var policy ApprovalPolicy
+		if err := json.Unmarshal(policyJSON, &policy); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal approval policy JSON: %v", err)
+		}
+		return &policy, nil
+	}
+	return nil, nil
+}
+
+func (s *SupplyChainContract) putPendingOperation(ctx contractapi.TransactionContextInterface, op *PendingOperation) error {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingOperationObjectType, []string{op.ID})
+	if err != nil {
+		return err
+	}
+
+	opJSON, err := marshalState(op)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, opJSON)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
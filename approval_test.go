@@ -0,0 +1,125 @@
+package smartcontract
+
+import (
+	"testing"
+)
+
+func TestSetApprovalPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		approvers string
+		threshold string
+		wantErr   string
+	}{
+		{"every approver", `["Org1MSP","Org2MSP"]`, "2", ""},
+		{"threshold above approvers", `["Org1MSP","Org2MSP"]`, "3", "threshold must be between 1 and 2, got 3"},
+		{"duplicate approver", `["Org1MSP","Org1MSP"]`, "2", "approver Org1MSP listed more than once"},
+		{"unknown approver", `["Org1MSP","Org3MSP"]`, "1", "Org3MSP is not the MSP ID of a registered participant"},
+		{"participant ID as approver", `["Org1MSP","CompanyB"]`, "1", "CompanyB is not the MSP ID of a registered participant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(admin, "SetApprovalPolicy", "Recall", "", tt.approvers, tt.threshold, "24")
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestApprovalPolicyCannotBeBypassed(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		step      txStep
+		wantOwner string
+	}{
+		{
+			name:      "owner change through UpdateProduct",
+			operation: "TransferOwnership",
+			step:      txStep{org1, "UpdateProduct", []string{"p1", "Manufactured", "CompanyB", "High-end gaming laptop", "Electronics"}, "requires approval"},
+			wantOwner: "CompanyA",
+		},
+		{
+			name:      "recall through UpdateProduct",
+			operation: "Recall",
+			step:      txStep{org1, "UpdateProduct", []string{"p1", "Recalled", "CompanyA", "High-end gaming laptop", "Electronics"}, "requires approval"},
+			wantOwner: "CompanyA",
+		},
+		{
+			name:      "ungoverned update",
+			operation: "Recall",
+			step:      txStep{org1, "UpdateProduct", []string{"p1", "Shipped", "CompanyA", "Refurbished laptop", "Electronics"}, ""},
+			wantOwner: "CompanyA",
+		},
+		{
+			name:      "owner change through TransferOwnership",
+			operation: "TransferOwnership",
+			step:      txStep{org1, "TransferOwnership", []string{"p1", "CompanyB"}, "requires approval"},
+			wantOwner: "CompanyA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "SetApprovalPolicy", tt.operation, "", `["Org1MSP","Org2MSP"]`, "2", "24")
+
+			l.run([]txStep{tt.step})
+
+			product := l.product("p1")
+			if product.Owner != tt.wantOwner || product.Status == "Recalled" {
+				t.Errorf("p1 = %s %s, want %s and not Recalled", product.Owner, product.Status, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestCompleteReturnRequiresApproval(t *testing.T) {
+	l := newSoldLedger(t)
+	l.submit(org1, "RequestReturn", "r1", "p2", "faulty")
+	l.submit(org2, "ApproveReturn", "r1")
+	l.submit(org2, "ReceiveReturn", "r1")
+	l.submit(org2, "InspectReturn", "r1")
+	l.submit(admin, "SetApprovalPolicy", "TransferOwnership", "", `["Org1MSP","Org2MSP"]`, "2", "24")
+
+	_, err := l.ledger.Submit(org2, "CompleteReturn", "r1", "Refurbished")
+	expectError(t, err, "requires approval")
+	if owner := l.product("p2").Owner; owner != "CompanyA" {
+		t.Errorf("p2 owner = %s, want CompanyA", owner)
+	}
+}
+
+func TestApprovedDeleteRemovesIndexes(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(admin, "RegisterLocation", "w1", "Warehouse 1", "warehouse", "", "0", "0", "CompanyA")
+	l.submit(org1, "AssignGS1Identifier", "p1", "4006381333931", "1", "7")
+	l.submit(org1, "CheckIn", "p1", "w1")
+	if _, err := l.ledger.SubmitTransient(org1, authSecret, "GenerateAuthenticityToken", "p1"); err != nil {
+		t.Fatal(err)
+	}
+	epc := l.product("p1").EPC
+
+	l.submit(admin, "SetApprovalPolicy", "DeleteProduct", "", `["Org1MSP","Org2MSP"]`, "2", "24")
+	l.submit(org1, "ProposeOperation", "op1", "DeleteProduct", "p1", `[]`)
+	l.submit(org1, "ApproveOperation", "op1")
+	l.submit(org2, "ApproveOperation", "op1")
+
+	if exists := string(l.submit(org1, "ProductExists", "p1")); exists != "false" {
+		t.Errorf("ProductExists(p1) = %s, want false", exists)
+	}
+	_, err := l.ledger.Evaluate(org1, "GetProductByEPC", epc)
+	expectError(t, err, "no product is identified by")
+
+	var products []*Product
+	l.evaluate(org1, &products, "GetProductsAtLocation", "w1")
+	if len(products) != 0 {
+		t.Errorf("products at w1 = %v, want none", products)
+	}
+
+	var result AuthenticityResult
+	l.evaluate(org3, &result, "VerifyAuthenticity", authCode("p1"))
+	if result.Authentic {
+		t.Errorf("VerifyAuthenticity = %+v, want the code to be unrecognised", result)
+	}
+}
@@ -0,0 +1,518 @@
+package smartcontract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	auditEntryObjectType = "AuditEntry"
+	auditHeadObjectType  = "AuditHead"
+	auditTimeObjectType  = "AuditTime"
+
+	// auditTimeRangeLimit bounds the span of GetAuditLogByTimeRange, which
+	// reads the time index one day at a time.
+	auditTimeRangeLimit = 31 * 24 * time.Hour
+
+	// Audit chain types for products, which are stored under simple keys,
+	// and for transactions that are chained by function name.
+	productAuditChain     = "Product"
+	transactionAuditChain = "Transaction"
+)
+
+// auditChains maps transactions whose first argument is the ID of the asset
+// they change to the type of that asset. Every asset has its own audit
+// chain, so that transactions on different assets do not contend for one
+// chain head; all other transactions are chained by function name.
+// SplitProduct and MergeProducts, which change several products, are
+// appended to the chain of each; see auditChainsFor.
+var auditChains = map[string]string{
+	"CreateProduct":               productAuditChain,
+	"CreateProductWithAttributes": productAuditChain,
+	"CreateStockProduct":          productAuditChain,
+	"UpdateProduct":               productAuditChain,
+	"TransferOwnership":           productAuditChain,
+	"SetProductAttributes":        productAuditChain,
+	"AssignGS1Identifier":         productAuditChain,
+	"GenerateAuthenticityToken":   productAuditChain,
+	"CheckIn":                     productAuditChain,
+	"CheckOut":                    productAuditChain,
+	"VerifyAuthenticity":          authTokenObjectType,
+	"CreatePurchaseOrder":         purchaseOrderObjectType,
+	"AcceptPurchaseOrder":         purchaseOrderObjectType,
+	"CancelPurchaseOrder":         purchaseOrderObjectType,
+	"FulfilPurchaseOrder":         purchaseOrderObjectType,
+	"IssueInvoice":                invoiceObjectType,
+	"IssueTransferInvoice":        invoiceObjectType,
+	"DisputeInvoice":              invoiceObjectType,
+	"ResolveInvoiceDispute":       invoiceObjectType,
+	"PayInvoice":                  invoiceObjectType,
+	"SettleInvoice":               invoiceObjectType,
+	"MintTokens":                  balanceObjectType,
+	"CreateEscrow":                escrowObjectType,
+	"SettleEscrow":                escrowObjectType,
+	"RefundEscrow":                escrowObjectType,
+	"RequestReturn":               returnObjectType,
+	"ApproveReturn":               returnObjectType,
+	"DenyReturn":                  returnObjectType,
+	"ReceiveReturn":               returnObjectType,
+	"InspectReturn":               returnObjectType,
+	"CompleteReturn":              returnObjectType,
+	"SubmitWarrantyClaim":         warrantyClaimObjectType,
+	"AdjudicateWarrantyClaim":     warrantyClaimObjectType,
+	"ProposeOperation":            pendingOperationObjectType,
+	"ApproveOperation":            pendingOperationObjectType,
+	"RejectOperation":             pendingOperationObjectType,
+	"RegisterParticipant":         participantObjectType,
+	"UpdateParticipant":           participantObjectType,
+	"SuspendParticipant":          participantObjectType,
+	"ReactivateParticipant":       participantObjectType,
+	"RegisterCategory":            categoryObjectType,
+	"UpdateCategorySchema":        categoryObjectType,
+	"RegisterLocation":            locationObjectType,
+	"RecordEmission":              emissionObjectType,
+}
+
+type AuditEntry struct {
+	ChainType  string    `json:"chain_type"`
+	ChainID    string    `json:"chain_id"`
+	Sequence   int64     `json:"sequence"`
+	TxID       string    `json:"tx_id"`
+	Function   string    `json:"function"`
+	Submitter  string    `json:"submitter"`
+	ArgsHash   string    `json:"args_hash"`
+	ResultHash string    `json:"result_hash"`
+	Timestamp  time.Time `json:"timestamp"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+// auditChainRef identifies one audit chain.
+type auditChainRef struct {
+	chainType string
+	chainID   string
+}
+
+// AuditChain is the head of one audit chain.
+type AuditChain struct {
+	ChainType string `json:"chain_type"`
+	ChainID   string `json:"chain_id"`
+	Sequence  int64  `json:"sequence"`
+	Hash      string `json:"hash"`
+}
+
+type auditHead struct {
+	Sequence int64  `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	BrokenAt int64  `json:"broken_at,omitempty" metadata:",optional"`
+	Reason   string `json:"reason,omitempty" metadata:",optional"`
+}
+
+// GetAuditChains lists the head of every audit chain.
+func (s *SupplyChainContract) GetAuditChains(ctx contractapi.TransactionContextInterface) ([]*AuditChain, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auditHeadObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	chains := []*AuditChain{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(attributes) != 2 {
+			continue
+		}
+		var head auditHead
+		if err := json.Unmarshal(queryResponse.Value, &head); err != nil {
+			return nil, err
+		}
+		chains = append(chains, &AuditChain{ChainType: attributes[0], ChainID: attributes[1], Sequence: head.Sequence, Hash: head.Hash})
+	}
+
+	return chains, nil
+}
+
+// GetAuditLog returns the entries of one audit chain with sequence numbers
+// from fromSeq to toSeq inclusive.
+func (s *SupplyChainContract) GetAuditLog(ctx contractapi.TransactionContextInterface, chainType, chainID string, fromSeq, toSeq int64) ([]*AuditEntry, error) {
+	if fromSeq < 1 || toSeq < fromSeq {
+		return nil, fmt.Errorf("invalid sequence range %d to %d", fromSeq, toSeq)
+	}
+
+	pageSize, err := s.pageSize(ctx, int32(toSeq-fromSeq+1))
+	if err != nil {
+		return nil, err
+	}
+	if toSeq-fromSeq+1 > int64(pageSize) {
+		toSeq = fromSeq + int64(pageSize) - 1
+	}
+
+	var entries []*AuditEntry
+	for seq := fromSeq; seq <= toSeq; seq++ {
+		entry, err := s.queryAuditEntry(ctx, chainType, chainID, seq)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// VerifyAuditChain recomputes every entry's hash in one audit chain and
+// checks that it links to its predecessor and that the chain ends at the
+// recorded head.
+func (s *SupplyChainContract) VerifyAuditChain(ctx contractapi.TransactionContextInterface, chainType, chainID string) (*AuditVerification, error) {
+	head, err := s.getAuditHead(ctx, chainType, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	prevHash := ""
+	for seq := int64(1); seq <= head.Sequence; seq++ {
+		entry, err := s.queryAuditEntry(ctx, chainType, chainID, seq)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return &AuditVerification{Entries: seq - 1, BrokenAt: seq, Reason: "entry is missing"}, nil
+		}
+		if entry.ChainType != chainType || entry.ChainID != chainID || entry.Sequence != seq {
+			return &AuditVerification{Entries: seq - 1, BrokenAt: seq, Reason: fmt.Sprintf("entry records sequence %d of chain %s %s", entry.Sequence, entry.ChainType, entry.ChainID)}, nil
+		}
+		if entry.PrevHash != prevHash {
+			return &AuditVerification{Entries: seq - 1, BrokenAt: seq, Reason: "previous hash does not match"}, nil
+		}
+		if entry.Hash != hashAuditEntry(entry) {
+			return &AuditVerification{Entries: seq - 1, BrokenAt: seq, Reason: "entry hash does not match its contents"}, nil
+		}
+		prevHash = entry.Hash
+	}
+
+	if prevHash != head.Hash {
+		return &AuditVerification{Entries: head.Sequence, BrokenAt: head.Sequence, Reason: "chain head hash does not match last entry"}, nil
+	}
+
+	return &AuditVerification{Valid: true, Entries: head.Sequence}, nil
+}
+
+// GetAuditLogByTimeRange returns the entries of every audit chain recorded in
+// [from, to), in the order they were recorded. Bounds are RFC3339 and may be
+// at most 31 days apart.
+func (s *SupplyChainContract) GetAuditLogByTimeRange(ctx contractapi.TransactionContextInterface, from, to string) ([]*AuditEntry, error) {
+	fromTime, err := time.Parse(time.RFC3339Nano, from)
+	if err != nil {
+		return nil, fmt.Errorf("from must be RFC3339: %v", err)
+	}
+	toTime, err := time.Parse(time.RFC3339Nano, to)
+	if err != nil {
+		return nil, fmt.Errorf("to must be RFC3339: %v", err)
+	}
+	if !toTime.After(fromTime) {
+		return nil, fmt.Errorf("to %s is not after from %s", to, from)
+	}
+	if toTime.Sub(fromTime) > auditTimeRangeLimit {
+		return nil, fmt.Errorf("time range must not exceed %d days", int(auditTimeRangeLimit/(24*time.Hour)))
+	}
+	fromTime, toTime = fromTime.UTC(), toTime.UTC()
+
+	entries := []*AuditEntry{}
+	for day := fromTime.Truncate(24 * time.Hour); day.Before(toTime); day = day.Add(24 * time.Hour) {
+		dayEntries, err := s.auditEntriesOn(ctx, day, fromTime, toTime)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, dayEntries...)
+	}
+
+	return entries, nil
+}
+
+// auditEntriesOn returns the entries indexed under day that were recorded in
+// [from, to).
+func (s *SupplyChainContract) auditEntriesOn(ctx contractapi.TransactionContextInterface, day, from, to time.Time) ([]*AuditEntry, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(auditTimeObjectType, []string{day.Format("2006-01-02")})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var entries []*AuditEntry
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(attributes) != 6 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(attributes[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed audit time index key %q: %v", queryResponse.Key, err)
+		}
+		if at := time.Unix(0, nanos); at.Before(from) || !at.Before(to) {
+			continue
+		}
+		seq, err := strconv.ParseInt(attributes[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed audit time index key %q: %v", queryResponse.Key, err)
+		}
+
+		entry, err := s.queryAuditEntry(ctx, attributes[3], attributes[4], seq)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// appendAuditEntry links a record of the current transaction onto each of its
+// audit chains. Concurrent transactions on the same chain read and write the
+// same head, so one of them will fail MVCC validation and must be retried;
+// they would conflict on the asset itself in any case.
+func (s *SupplyChainContract) appendAuditEntry(ctx contractapi.TransactionContextInterface, function string, result interface{}) error {
+	_, args := ctx.GetStub().GetFunctionAndParameters()
+	for _, chain := range auditChainsFor(function, args) {
+		if err := s.appendAuditEntryTo(ctx, chain.chainType, chain.chainID, function, args, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SupplyChainContract) appendAuditEntryTo(ctx contractapi.TransactionContextInterface, chainType, chainID, function string, args []string, result interface{}) error {
+	head, err := s.getAuditHead(ctx, chainType, chainID)
+	if err != nil {
+		return err
+	}
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	argsJSON, err := marshalState(args)
+	if err != nil {
+		return err
+	}
+	resultJSON, err := marshalState(result)
+	if err != nil {
+		return err
+	}
+
+	entry := &AuditEntry{
+		ChainType:  chainType,
+		ChainID:    chainID,
+		Sequence:   head.Sequence + 1,
+		TxID:       ctx.GetStub().GetTxID(),
+		Function:   function,
+		Submitter:  submitter,
+		ArgsHash:   sha256Hex(argsJSON),
+		ResultHash: sha256Hex(resultJSON),
+		Timestamp:  txTime,
+		PrevHash:   head.Hash,
+	}
+	entry.Hash = hashAuditEntry(entry)
+
+	key, err := auditEntryKey(ctx, chainType, chainID, entry.Sequence)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := marshalState(entry)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, entryJSON); err != nil {
+		return fmt.Errorf("failed to put audit entry into ledger: %v", err)
+	}
+
+	// The time index is bucketed by day because Fabric only range-scans
+	// composite keys by prefix; the zero-padded time orders each day.
+	timeKey, err := ctx.GetStub().CreateCompositeKey(auditTimeObjectType, []string{
+		txTime.UTC().Format("2006-01-02"),
+		fmt.Sprintf("%020d", txTime.UnixNano()),
+		entry.TxID,
+		chainType,
+		chainID,
+		fmt.Sprintf("%020d", entry.Sequence),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(timeKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to put audit time index into ledger: %v", err)
+	}
+
+	headKey, err := ctx.GetStub().CreateCompositeKey(auditHeadObjectType, []string{chainType, chainID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	headJSON, err := marshalState(auditHead{Sequence: entry.Sequence, Hash: entry.Hash})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(headKey, headJSON)
+}
+
+// auditChainsFor returns the audit chains for an invocation of function: the
+// chains of every product SplitProduct or MergeProducts changes, and a single
+// chain for every other transaction. A chain is listed once however often its
+// ID appears in the arguments.
+func auditChainsFor(function string, args []string) []auditChainRef {
+	var productIDs []string
+	switch function {
+	case "SplitProduct":
+		var children []string
+		if len(args) >= 2 && json.Unmarshal([]byte(args[1]), &children) == nil {
+			productIDs = append([]string{args[0]}, children...)
+		}
+	case "MergeProducts":
+		var sources []string
+		if len(args) >= 2 && json.Unmarshal([]byte(args[0]), &sources) == nil {
+			productIDs = append(sources, args[1])
+		}
+	default:
+		chainType, chainID := auditChainFor(function, args)
+		return []auditChainRef{{chainType, chainID}}
+	}
+	if len(productIDs) == 0 {
+		return []auditChainRef{{transactionAuditChain, function}}
+	}
+
+	var chains []auditChainRef
+	seen := make(map[string]bool)
+	for _, id := range productIDs {
+		if !seen[id] {
+			seen[id] = true
+			chains = append(chains, auditChainRef{productAuditChain, id})
+		}
+	}
+	return chains
+}
+
+// auditChainFor returns the audit chain for an invocation of function that
+// changes at most one asset.
+func auditChainFor(function string, args []string) (string, string) {
+	chainType, ok := auditChains[function]
+	if !ok || len(args) == 0 {
+		return transactionAuditChain, function
+	}
+	if chainType == authTokenObjectType {
+		// Tokens are identified by the hash of their code, which must not
+		// itself appear in a key.
+		return chainType, sha256Hex([]byte(args[0]))
+	}
+	return chainType, args[0]
+}
+
+func (s *SupplyChainContract) getAuditHead(ctx contractapi.TransactionContextInterface, chainType, chainID string) (*auditHead, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(auditHeadObjectType, []string{chainType, chainID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	headJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit head from ledger: %v", err)
+	}
+	if headJSON == nil {
+		return &auditHead{}, nil
+	}
+
+	var head auditHead
+	err = json.Unmarshal(headJSON, &head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit head JSON: %v", err)
+	}
+
+	return &head, nil
+}
+
+func (s *SupplyChainContract) queryAuditEntry(ctx contractapi.TransactionContextInterface, chainType, chainID string, seq int64) (*AuditEntry, error) {
+	key, err := auditEntryKey(ctx, chainType, chainID, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit entry from ledger: %v", err)
+	}
+	if entryJSON == nil {
+		return nil, nil
+	}
+
+	var entry AuditEntry
+	err = json.Unmarshal(entryJSON, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit entry JSON: %v", err)
+	}
+
+	return &entry, nil
+}
+
+// auditEntryKey zero-pads the sequence so each chain's entries sort
+// chronologically.
+func auditEntryKey(ctx contractapi.TransactionContextInterface, chainType, chainID string, seq int64) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(auditEntryObjectType, []string{chainType, chainID, fmt.Sprintf("%020d", seq)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return key, nil
+}
+
+func hashAuditEntry(entry *AuditEntry) string {
+	fields := []string{
+		entry.ChainType,
+		entry.ChainID,
+		strconv.FormatInt(entry.Sequence, 10),
+		entry.TxID,
+		entry.Function,
+		entry.Submitter,
+		entry.ArgsHash,
+		entry.ResultHash,
+		entry.Timestamp.Format(time.RFC3339Nano),
+		entry.PrevHash,
+	}
+	return sha256Hex([]byte(strings.Join(fields, "\x00")))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
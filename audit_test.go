@@ -0,0 +1,158 @@
+package smartcontract
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditChains(t *testing.T) {
+	tests := []struct {
+		name     string
+		function string
+		args     []string
+		want     []auditChainRef
+	}{
+		{"product", "TransferOwnership", []string{"p1", "CompanyB"}, []auditChainRef{{"Product", "p1"}}},
+		{"purchase order", "CancelPurchaseOrder", []string{"po1"}, []auditChainRef{{"PurchaseOrder", "po1"}}},
+		{"token code is hashed", "VerifyAuthenticity", []string{"secret"}, []auditChainRef{{"AuthToken", sha256Hex([]byte("secret"))}}},
+		{"split", "SplitProduct", []string{"s1", `["c1","c2"]`, "[10,20]"}, []auditChainRef{{"Product", "s1"}, {"Product", "c1"}, {"Product", "c2"}}},
+		{"merge", "MergeProducts", []string{`["c1","c2"]`, "m1"}, []auditChainRef{{"Product", "c1"}, {"Product", "c2"}, {"Product", "m1"}}},
+		{"repeated product", "MergeProducts", []string{`["c1","c1"]`, "c1"}, []auditChainRef{{"Product", "c1"}}},
+		{"unmapped transaction", "SetPaused", []string{"true"}, []auditChainRef{{"Transaction", "SetPaused"}}},
+		{"no arguments", "CreateProduct", nil, []auditChainRef{{"Transaction", "CreateProduct"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditChainsFor(tt.function, tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditChainsFor(%s) = %v, want %v", tt.function, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org1, "TransferOwnership", "p1", "CompanyB")
+	l.submit(org2, "TransferOwnership", "p2", "CompanyA")
+	l.submit(org2, "TransferOwnership", "p1", "CompanyA")
+
+	tests := []struct {
+		product   string
+		functions []string
+	}{
+		{"p1", []string{"TransferOwnership", "TransferOwnership"}},
+		{"p2", []string{"TransferOwnership"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.product, func(t *testing.T) {
+			var entries []*AuditEntry
+			l.evaluate(auditor, &entries, "GetAuditLog", "Product", tt.product, "1", "10")
+			if len(entries) != len(tt.functions) {
+				t.Fatalf("audit log has %d entries, want %d", len(entries), len(tt.functions))
+			}
+			prevHash := ""
+			for i, entry := range entries {
+				if entry.Sequence != int64(i+1) || entry.Function != tt.functions[i] || entry.PrevHash != prevHash {
+					t.Errorf("entry %d = %+v, want sequence %d of %s linked to %q", i, entry, i+1, tt.functions[i], prevHash)
+				}
+				prevHash = entry.Hash
+			}
+
+			var verification AuditVerification
+			l.evaluate(auditor, &verification, "VerifyAuditChain", "Product", tt.product)
+			if !verification.Valid || verification.Entries != int64(len(tt.functions)) {
+				t.Errorf("verification = %+v, want %d valid entries", verification, len(tt.functions))
+			}
+		})
+	}
+
+	var chains []*AuditChain
+	l.evaluate(auditor, &chains, "GetAuditChains")
+	heads := map[string]int64{}
+	for _, chain := range chains {
+		heads[chain.ChainType+" "+chain.ChainID] = chain.Sequence
+	}
+	if heads["Product p1"] != 2 || heads["Product p2"] != 1 || heads["Transaction InitLedger"] != 1 {
+		t.Errorf("audit chain heads = %v, want p1 at 2, p2 at 1 and InitLedger at 1", heads)
+	}
+}
+
+func TestVerifyEmptyAuditChain(t *testing.T) {
+	l := newTestLedger(t)
+
+	var verification AuditVerification
+	l.evaluate(auditor, &verification, "VerifyAuditChain", "Product", "p9")
+	if !verification.Valid || verification.Entries != 0 {
+		t.Errorf("verification = %+v, want a valid empty chain", verification)
+	}
+}
+
+func TestStockAuditLog(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "100", "kg")
+	l.submit(org1, "SplitProduct", "s1", `["c1","c2"]`, `[10,20]`)
+	l.submit(org1, "MergeProducts", `["c1","c2"]`, "m1")
+
+	tests := []struct {
+		product   string
+		functions []string
+	}{
+		{"s1", []string{"CreateStockProduct", "SplitProduct"}},
+		{"c1", []string{"SplitProduct", "MergeProducts"}},
+		{"m1", []string{"MergeProducts"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.product, func(t *testing.T) {
+			var entries []*AuditEntry
+			l.evaluate(auditor, &entries, "GetAuditLog", "Product", tt.product, "1", "10")
+			var functions []string
+			for _, entry := range entries {
+				functions = append(functions, entry.Function)
+			}
+			if !reflect.DeepEqual(functions, tt.functions) {
+				t.Errorf("audit log functions = %v, want %v", functions, tt.functions)
+			}
+		})
+	}
+}
+
+func TestAuditLogByTimeRange(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org1, "TransferOwnership", "p1", "CompanyB")
+	l.submit(org2, "TransferOwnership", "p2", "CompanyA")
+
+	now := time.Now().UTC()
+	from, to := now.Add(-time.Hour).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339)
+
+	var entries []*AuditEntry
+	l.evaluate(auditor, &entries, "GetAuditLogByTimeRange", from, to)
+	var got []string
+	for i, entry := range entries {
+		got = append(got, entry.Function+" "+entry.ChainID)
+		if i > 0 && entry.Timestamp.Before(entries[i-1].Timestamp) {
+			t.Errorf("entry %d at %s precedes entry %d at %s", i, entry.Timestamp, i-1, entries[i-1].Timestamp)
+		}
+	}
+	want := []string{"InitLedger InitLedger", "TransferOwnership p1", "TransferOwnership p2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+
+	l.evaluate(auditor, &entries, "GetAuditLogByTimeRange", now.Add(time.Hour).Format(time.RFC3339), now.Add(2*time.Hour).Format(time.RFC3339))
+	if len(entries) != 0 {
+		t.Errorf("entries after now = %v, want none", entries)
+	}
+
+	for _, tt := range []struct{ from, to, wantErr string }{
+		{"yesterday", to, "from must be RFC3339"},
+		{to, from, "is not after from"},
+		{"2024-01-01T00:00:00Z", "2024-03-01T00:00:00Z", "time range must not exceed 31 days"},
+	} {
+		_, err := l.ledger.Evaluate(auditor, "GetAuditLogByTimeRange", tt.from, tt.to)
+		expectError(t, err, tt.wantErr)
+	}
+}
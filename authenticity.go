@@ -0,0 +1,292 @@
+package smartcontract
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	authTokenObjectType        = "AuthToken"
+	authTokenProductObjectType = "AuthTokenProduct"
+	authSecretTransientKey     = "auth_secret"
+)
+
+type authToken struct {
+	ProductID   string    `json:"product_id"`
+	CodeHash    string    `json:"code_hash"`
+	Revoked     bool      `json:"revoked"`
+	ScanCount   int32     `json:"scan_count"`
+	FirstScanAt time.Time `json:"first_scan_at"`
+	LastScanAt  time.Time `json:"last_scan_at"`
+}
+
+type PublicProductView struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	GTIN     string `json:"gtin,omitempty" metadata:",optional"`
+}
+
+type ProvenanceStep struct {
+	Timestamp time.Time `json:"timestamp"`
+	Event     string    `json:"event"`
+	Status    string    `json:"status"`
+}
+
+type AuthenticityResult struct {
+	Authentic            bool               `json:"authentic"`
+	Product              *PublicProductView `json:"product,omitempty" metadata:",optional"`
+	Provenance           []ProvenanceStep   `json:"provenance,omitempty" metadata:",optional"`
+	ScanCount            int32              `json:"scan_count"`
+	FirstScanAt          time.Time          `json:"first_scan_at" metadata:",optional"`
+	CounterfeitSuspected bool               `json:"counterfeit_suspected"`
+	Message              string             `json:"message"`
+}
+
+// GenerateAuthenticityToken derives the code printed on a product's packaging
+// as the first 16 hex digits of HMAC-SHA256(secret, product ID), and stores
+// only its hash. The secret is passed in the transient map under
+// "auth_secret" so it never reaches the ledger; the manufacturer computes the
+// same code off-chain for printing. Generating a token from a new secret
+// revokes the old one; a code that has already been issued is never issued
+// again, so that its scan history and revocation cannot be reset.
+func (s *SupplyChainContract) GenerateAuthenticityToken(ctx contractapi.TransactionContextInterface, productID string) error {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to read transient data: %v", err)
+	}
+	secret, ok := transient[authSecretTransientKey]
+	if !ok || len(secret) < 16 {
+		return fmt.Errorf("transient field %s must hold a secret of at least 16 bytes", authSecretTransientKey)
+	}
+
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := s.ownerMSPID(ctx, product.Owner)
+	if err != nil {
+		return err
+	}
+	if submitter != mspID {
+		return fmt.Errorf("only the owner of product %s can generate its authenticity token", productID)
+	}
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(productID))
+	code := hex.EncodeToString(mac.Sum(nil))[:16]
+	codeHash := sha256Hex([]byte(code))
+
+	existing, err := s.queryAuthToken(ctx, codeHash)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("this authenticity code has already been issued for product %s; use a new secret to replace it", productID)
+	}
+
+	productKey, err := ctx.GetStub().CreateCompositeKey(authTokenProductObjectType, []string{productID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	previousHash, err := ctx.GetStub().GetState(productKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if previousHash != nil {
+		previous, err := s.queryAuthToken(ctx, string(previousHash))
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			previous.Revoked = true
+			if err := s.putAuthToken(ctx, previous); err != nil {
+				return fmt.Errorf("failed to revoke previous token: %v", err)
+			}
+		}
+	}
+
+	token := authToken{ProductID: productID, CodeHash: codeHash}
+	if err := s.putAuthToken(ctx, &token); err != nil {
+		return fmt.Errorf("failed to put authenticity token into ledger: %v", err)
+	}
+
+	return ctx.GetStub().PutState(productKey, []byte(codeHash))
+}
+
+// VerifyAuthenticity checks a code presented by a consumer and returns a
+// public-safe view of the product and its provenance. It is submitted rather
+// than evaluated so that each scan is counted: a code scanned more often than
+// the configured limit is flagged as a likely copy.
+func (s *SupplyChainContract) VerifyAuthenticity(ctx contractapi.TransactionContextInterface, code string) (*AuthenticityResult, error) {
+	token, err := s.queryAuthToken(ctx, sha256Hex([]byte(code)))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return &AuthenticityResult{Message: "code is not recognised; this product may be counterfeit"}, nil
+	}
+	if token.Revoked {
+		return &AuthenticityResult{Message: "code has been revoked; this product may be counterfeit"}, nil
+	}
+
+	product, err := s.readProduct(ctx, token.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	provenance, err := s.publicProvenance(ctx, token.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ScanCount++
+	if token.FirstScanAt.IsZero() {
+		token.FirstScanAt = txTime
+	}
+	token.LastScanAt = txTime
+	if err := s.putAuthToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to record scan: %v", err)
+	}
+
+	result := &AuthenticityResult{
+		Authentic:   true,
+		Product:     publicProductView(product),
+		Provenance:  provenance,
+		ScanCount:   token.ScanCount,
+		FirstScanAt: token.FirstScanAt,
+		Message:     "product is authentic",
+	}
+	if token.ScanCount > config.MaxAuthenticityScans {
+		result.CounterfeitSuspected = true
+		result.Message = fmt.Sprintf("code has been scanned %d times since %s; this product may be a copy", token.ScanCount, token.FirstScanAt.Format(time.RFC3339))
+	}
+
+	return result, nil
+}
+
+func publicProductView(product *Product) *PublicProductView {
+	return &PublicProductView{
+		ID:       product.ID,
+		Name:     product.Name,
+		Category: product.Category,
+		Status:   product.Status,
+		GTIN:     product.GTIN,
+	}
+}
+
+// publicProvenance summarises the product's history without naming owners
+// or locations.
+func (s *SupplyChainContract) publicProvenance(ctx contractapi.TransactionContextInterface, productID string) ([]ProvenanceStep, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	type version struct {
+		at      time.Time
+		product *Product
+	}
+	var versions []version
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if modification.IsDelete {
+			continue
+		}
+
+		var v version
+		if modification.Timestamp != nil {
+			v.at = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
+		if v.product, err = unmarshalProduct(modification.Value); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].at.Before(versions[j].at) })
+
+	var steps []ProvenanceStep
+	for i, v := range versions {
+		event := "Updated"
+		switch {
+		case i == 0:
+			event = "Manufactured"
+		case versions[i-1].product.Owner != v.product.Owner:
+			event = "Changed hands"
+		case versions[i-1].product.Location != v.product.Location:
+			event = "Moved"
+		case versions[i-1].product.Status != v.product.Status:
+			event = "Status changed"
+		}
+		steps = append(steps, ProvenanceStep{
+			Timestamp: v.at,
+			Event:     event,
+			Status:    v.product.Status,
+		})
+	}
+
+	return steps, nil
+}
+
+func (s *SupplyChainContract) queryAuthToken(ctx contractapi.TransactionContextInterface, codeHash string) (*authToken, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(authTokenObjectType, []string{codeHash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	tokenJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authenticity token from ledger: %v", err)
+	}
+	if tokenJSON == nil {
+		return nil, nil
+	}
+
+	var token authToken
+	err = json.Unmarshal(tokenJSON, &token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal authenticity token JSON: %v", err)
+	}
+
+	return &token, nil
+}
+
+func (s *SupplyChainContract) putAuthToken(ctx contractapi.TransactionContextInterface, token *authToken) error {
+	key, err := ctx.GetStub().CreateCompositeKey(authTokenObjectType, []string{token.CodeHash})
+	if err != nil {
+		return err
+	}
+
+	tokenJSON, err := marshalState(token)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, tokenJSON)
+}
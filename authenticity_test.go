@@ -0,0 +1,145 @@
+package smartcontract
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+// authSecret and rotatedSecret are the manufacturer's secrets for
+// authenticity tokens.
+var (
+	authSecret    = map[string][]byte{"auth_secret": []byte("0123456789abcdef")}
+	rotatedSecret = map[string][]byte{"auth_secret": []byte("fedcba9876543210")}
+)
+
+// authCode is the code GenerateAuthenticityToken derives from authSecret
+// for a product.
+func authCode(productID string) string {
+	return authCodeWith(authSecret, productID)
+}
+
+func authCodeWith(secret map[string][]byte, productID string) string {
+	mac := hmac.New(sha256.New, secret["auth_secret"])
+	mac.Write([]byte(productID))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// verify submits a scan of code and returns its result.
+func (l *testLedger) verify(code string) *AuthenticityResult {
+	l.t.Helper()
+	var result AuthenticityResult
+	if err := json.Unmarshal(l.submit(org3, "VerifyAuthenticity", code), &result); err != nil {
+		l.t.Fatalf("failed to decode VerifyAuthenticity result: %v", err)
+	}
+	return &result
+}
+
+func TestGenerateAuthenticityToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		secret    map[string][]byte
+		product   string
+		wantErr   string
+	}{
+		{"owner", org1, authSecret, "p1", ""},
+		{"not owner", org2, authSecret, "p1", "only the owner of product p1 can generate its authenticity token"},
+		{"no secret", org1, nil, "p1", "transient field auth_secret must hold a secret of at least 16 bytes"},
+		{"short secret", org1, map[string][]byte{"auth_secret": []byte("short")}, "p1", "at least 16 bytes"},
+		{"unknown product", org1, authSecret, "p9", "does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.SubmitTransient(tt.submitter, tt.secret, "GenerateAuthenticityToken", tt.product)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRegenerateAuthenticityToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		secrets       []map[string][]byte
+		wantErr       string
+		wantAuthentic bool
+		wantScans     int32
+		wantRotated   bool
+	}{
+		{"same secret keeps the scan count", []map[string][]byte{authSecret}, "this authenticity code has already been issued for product p1", true, 2, false},
+		{"new secret revokes the old code", []map[string][]byte{rotatedSecret}, "", false, 0, true},
+		{"revoked code stays revoked", []map[string][]byte{rotatedSecret, authSecret}, "this authenticity code has already been issued for product p1", false, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			if _, err := l.ledger.SubmitTransient(org1, authSecret, "GenerateAuthenticityToken", "p1"); err != nil {
+				t.Fatal(err)
+			}
+			l.verify(authCode("p1"))
+
+			var err error
+			for _, secret := range tt.secrets {
+				_, err = l.ledger.SubmitTransient(org1, secret, "GenerateAuthenticityToken", "p1")
+			}
+			expectError(t, err, tt.wantErr)
+
+			if result := l.verify(authCode("p1")); result.Authentic != tt.wantAuthentic || result.ScanCount != tt.wantScans {
+				t.Errorf("original code = %+v, want authentic %t with %d scans", result, tt.wantAuthentic, tt.wantScans)
+			}
+			if result := l.verify(authCodeWith(rotatedSecret, "p1")); result.Authentic != tt.wantRotated {
+				t.Errorf("rotated code = %+v, want authentic %t", result, tt.wantRotated)
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticity(t *testing.T) {
+	l := newTestLedger(t)
+	if _, err := l.ledger.SubmitTransient(org1, authSecret, "GenerateAuthenticityToken", "p1"); err != nil {
+		t.Fatal(err)
+	}
+	l.submit(org1, "TransferOwnership", "p1", "CompanyB")
+
+	tests := []struct {
+		name        string
+		code        string
+		authentic   bool
+		scanCount   int32
+		counterfeit bool
+	}{
+		{"first scan", authCode("p1"), true, 1, false},
+		{"second scan", authCode("p1"), true, 2, false},
+		{"third scan", authCode("p1"), true, 3, false},
+		{"over the limit", authCode("p1"), true, 4, true},
+		{"unknown code", authCode("p2"), false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := l.verify(tt.code)
+			if result.Authentic != tt.authentic || result.ScanCount != tt.scanCount || result.CounterfeitSuspected != tt.counterfeit {
+				t.Errorf("VerifyAuthenticity = %+v, want authentic %t after %d scans, counterfeit suspected %t", result, tt.authentic, tt.scanCount, tt.counterfeit)
+			}
+		})
+	}
+
+	result := l.verify(authCode("p1"))
+	if result.Product == nil || result.Product.ID != "p1" {
+		t.Fatalf("product = %+v, want p1", result.Product)
+	}
+	var events []string
+	for _, step := range result.Provenance {
+		events = append(events, step.Event)
+	}
+	if len(events) != 2 || events[0] != "Manufactured" || events[1] != "Changed hands" {
+		t.Errorf("provenance = %v, want Manufactured then Changed hands", events)
+	}
+}
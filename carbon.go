@@ -0,0 +1,281 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	emissionObjectType        = "Emission"
+	emissionProductObjectType = "EmissionProduct"
+)
+
+// Emissions are held in whole grams of CO2e so that allocating, splitting
+// and merging them is exact and every peer computes the same result.
+type EmissionRecord struct {
+	ID          string           `json:"id"`
+	Scope       string           `json:"scope"`
+	Target      string           `json:"target"`
+	Stage       string           `json:"stage"`
+	GramsCO2e   int64            `json:"grams_co2e"`
+	Methodology string           `json:"methodology"`
+	Allocation  map[string]int64 `json:"allocation"`
+	RecordedBy  string           `json:"recorded_by"`
+	Timestamp   time.Time        `json:"timestamp"`
+}
+
+type ProductFootprint struct {
+	ProductID      string            `json:"product_id"`
+	TotalGramsCO2e int64             `json:"total_grams_co2e"`
+	ByStage        map[string]int64  `json:"by_stage"`
+	Records        []*EmissionRecord `json:"records"`
+}
+
+var emissionStages = []string{"manufacturing", "transport", "storage", "packaging", "other"}
+
+// RecordEmission attributes gramsCO2e to a product (scope "product", target
+// is the product ID) or to a shipment (scope "shipment", target is its SSCC),
+// in which case it is allocated across productIDs by quantity. Each product
+// carries its cumulative footprint, which split and merge divide and combine.
+func (s *SupplyChainContract) RecordEmission(ctx contractapi.TransactionContextInterface, id, scope, target string, productIDs []string, stage string, gramsCO2e int64, methodology string) error {
+	if !containsString(emissionStages, stage) {
+		return fmt.Errorf("stage must be one of %v, got %q", emissionStages, stage)
+	}
+	if gramsCO2e <= 0 {
+		return fmt.Errorf("emission must be positive, got %d gCO2e", gramsCO2e)
+	}
+	if methodology == "" {
+		return fmt.Errorf("a methodology reference is required")
+	}
+
+	switch scope {
+	case "product":
+		productIDs = []string{target}
+	case "shipment":
+		if err := validateGS1Number("SSCC", target, 18); err != nil {
+			return err
+		}
+		if len(productIDs) == 0 {
+			return fmt.Errorf("shipment emissions must list the products carried")
+		}
+	default:
+		return fmt.Errorf("scope must be product or shipment, got %q", scope)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(emissionObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON != nil {
+		return fmt.Errorf("emission record with ID %s already exists", id)
+	}
+
+	var products []*Product
+	var quantities []int64
+	seen := make(map[string]bool)
+	for _, productID := range productIDs {
+		if seen[productID] {
+			return fmt.Errorf("product %s listed more than once", productID)
+		}
+		seen[productID] = true
+
+		product, err := s.readProduct(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if product.Quantity <= 0 {
+			return fmt.Errorf("product %s has no remaining quantity to carry emissions", productID)
+		}
+		products = append(products, product)
+		quantities = append(quantities, product.Quantity)
+	}
+
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	record := EmissionRecord{
+		ID:          id,
+		Scope:       scope,
+		Target:      target,
+		Stage:       stage,
+		GramsCO2e:   gramsCO2e,
+		Methodology: methodology,
+		Allocation:  make(map[string]int64),
+		RecordedBy:  submitter,
+		Timestamp:   txTime,
+	}
+
+	shares := allocateGrams(gramsCO2e, quantities)
+	for i, product := range products {
+		record.Allocation[product.ID] = shares[i]
+
+		product.Footprint = addFootprint(product.Footprint, map[string]int64{stage: shares[i]})
+		product.UpdatedAt = txTime
+		if err := s.putProduct(ctx, product); err != nil {
+			return fmt.Errorf("failed to update product: %v", err)
+		}
+
+		indexKey, err := ctx.GetStub().CreateCompositeKey(emissionProductObjectType, []string{product.ID, id})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+			return fmt.Errorf("failed to put emission index into ledger: %v", err)
+		}
+	}
+
+	recordJSON, err = marshalState(record)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, recordJSON)
+}
+
+// GetProductFootprint returns the product's cumulative footprint, including
+// emissions inherited through splits and merges, along with the records
+// allocated directly to it.
+func (s *SupplyChainContract) GetProductFootprint(ctx contractapi.TransactionContextInterface, id string) (*ProductFootprint, error) {
+	product, err := s.readProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	footprint := &ProductFootprint{
+		ProductID: id,
+		ByStage:   make(map[string]int64),
+		Records:   []*EmissionRecord{},
+	}
+	for stage, grams := range product.Footprint {
+		footprint.ByStage[stage] = grams
+		footprint.TotalGramsCO2e += grams
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(emissionProductObjectType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(attributes) != 2 {
+			continue
+		}
+		record, err := s.queryEmission(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		footprint.Records = append(footprint.Records, record)
+	}
+
+	return footprint, nil
+}
+
+func (s *SupplyChainContract) queryEmission(ctx contractapi.TransactionContextInterface, id string) (*EmissionRecord, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(emissionObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read emission record from ledger: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, fmt.Errorf("the emission record with ID %s does not exist", id)
+	}
+
+	var record EmissionRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal emission record JSON: %v", err)
+	}
+
+	return &record, nil
+}
+
+// allocateGrams divides total grams in proportion to weights by the largest
+// remainder method, so the shares are whole grams that add up to total.
+// Ties go to the earlier weight.
+func allocateGrams(total int64, weights []int64) []int64 {
+	shares := make([]int64, len(weights))
+	var sum int64
+	for _, weight := range weights {
+		sum += weight
+	}
+	if sum <= 0 {
+		return shares
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		quotient, remainder := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(total), big.NewInt(weight)), big.NewInt(sum), new(big.Int))
+		shares[i] = quotient.Int64()
+		remainders[i] = remainder.Int64()
+		allocated += shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:total-allocated] {
+		shares[i]++
+	}
+	return shares
+}
+
+// splitFootprint divides a footprint in proportion to quantities, stage by
+// stage, so that the parts add up to the whole.
+func splitFootprint(footprint map[string]int64, quantities []int64) []map[string]int64 {
+	parts := make([]map[string]int64, len(quantities))
+	for stage, grams := range footprint {
+		for i, share := range allocateGrams(grams, quantities) {
+			if share == 0 {
+				continue
+			}
+			if parts[i] == nil {
+				parts[i] = make(map[string]int64)
+			}
+			parts[i][stage] = share
+		}
+	}
+	return parts
+}
+
+func addFootprint(total, addition map[string]int64) map[string]int64 {
+	if len(addition) == 0 {
+		return total
+	}
+	if total == nil {
+		total = make(map[string]int64, len(addition))
+	}
+	for stage, grams := range addition {
+		total[stage] += grams
+	}
+	return total
+}
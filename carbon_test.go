@@ -0,0 +1,175 @@
+package smartcontract
+
+import (
+	"reflect"
+	"testing"
+)
+
+const shipmentSSCC = "106141412345678908"
+
+func TestRecordEmission(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      string
+		target     string
+		productIDs string
+		stage      string
+		gramsCO2e  string
+		wantErr    string
+	}{
+		{"product", "product", "p1", `[]`, "manufacturing", "12500", ""},
+		{"shipment", "shipment", shipmentSSCC, `["p1","p2"]`, "transport", "4", ""},
+		{"unknown stage", "product", "p1", `[]`, "disposal", "1", "stage must be one of"},
+		{"not positive", "product", "p1", `[]`, "transport", "0", "emission must be positive"},
+		{"fractional grams", "product", "p1", `[]`, "transport", "12.5", "cannot convert passed value 12.5 to int64"},
+		{"unknown scope", "pallet", "p1", `[]`, "transport", "1", "scope must be product or shipment"},
+		{"bad SSCC", "shipment", "106141412345678909", `["p1"]`, "transport", "1", "has check digit 9, expected 8"},
+		{"shipment without products", "shipment", shipmentSSCC, `[]`, "transport", "1", "must list the products carried"},
+		{"product listed twice", "shipment", shipmentSSCC, `["p1","p1"]`, "transport", "1", "product p1 listed more than once"},
+		{"unknown product", "product", "p9", `[]`, "transport", "1", "does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(org1, "RecordEmission", "e1", tt.scope, tt.target, tt.productIDs, tt.stage, tt.gramsCO2e, "GHG Protocol")
+			expectError(t, err, tt.wantErr)
+		})
+	}
+
+	l := newTestLedger(t)
+	l.run([]txStep{
+		{org1, "RecordEmission", []string{"e1", "product", "p1", `[]`, "transport", "1", ""}, "a methodology reference is required"},
+		{org1, "RecordEmission", []string{"e1", "product", "p1", `[]`, "transport", "1", "GHG Protocol"}, ""},
+		{org1, "RecordEmission", []string{"e1", "product", "p1", `[]`, "transport", "1", "GHG Protocol"}, "emission record with ID e1 already exists"},
+	})
+}
+
+func TestGetProductFootprint(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []txStep
+		productID string
+		wantTotal int64
+		byStage   map[string]int64
+		records   int
+	}{
+		{
+			name: "product and shipment emissions",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e1", "product", "s1", `[]`, "manufacturing", "50", "GHG Protocol"}, ""},
+				{org1, "RecordEmission", []string{"e2", "shipment", shipmentSSCC, `["s1","s2"]`, "transport", "30", "GLEC"}, ""},
+			},
+			productID: "s1",
+			wantTotal: 70,
+			byStage:   map[string]int64{"manufacturing": 50, "transport": 20},
+			records:   2,
+		},
+		{
+			name: "allocated by quantity",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e2", "shipment", shipmentSSCC, `["s1","s2"]`, "transport", "30", "GLEC"}, ""},
+			},
+			productID: "s2",
+			wantTotal: 10,
+			byStage:   map[string]int64{"transport": 10},
+			records:   1,
+		},
+		{
+			name: "inherited by split children",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e1", "product", "s1", `[]`, "manufacturing", "50", "GHG Protocol"}, ""},
+				{org1, "SplitProduct", []string{"s1", `["c1"]`, `[40]`}, ""},
+			},
+			productID: "c1",
+			wantTotal: 20,
+			byStage:   map[string]int64{"manufacturing": 20},
+		},
+		{
+			name: "remainder stays on the parent",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e1", "product", "s1", `[]`, "manufacturing", "50", "GHG Protocol"}, ""},
+				{org1, "SplitProduct", []string{"s1", `["c1"]`, `[40]`}, ""},
+			},
+			productID: "s1",
+			wantTotal: 30,
+			byStage:   map[string]int64{"manufacturing": 30},
+			records:   1,
+		},
+		{
+			name: "combined by merge",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e1", "product", "s1", `[]`, "manufacturing", "50", "GHG Protocol"}, ""},
+				{org1, "RecordEmission", []string{"e2", "product", "s2", `[]`, "storage", "5", "GHG Protocol"}, ""},
+				{org1, "MergeProducts", []string{`["s1","s2"]`, "m1"}, ""},
+			},
+			productID: "m1",
+			wantTotal: 55,
+			byStage:   map[string]int64{"manufacturing": 50, "storage": 5},
+		},
+		{
+			name: "shipment rounding conserves the total",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e2", "shipment", shipmentSSCC, `["s1","s2"]`, "transport", "10", "GLEC"}, ""},
+			},
+			productID: "s1",
+			wantTotal: 7,
+			byStage:   map[string]int64{"transport": 7},
+			records:   1,
+		},
+		{
+			name: "split rounding conserves the total",
+			steps: []txStep{
+				{org1, "RecordEmission", []string{"e1", "product", "s1", `[]`, "manufacturing", "50", "GHG Protocol"}, ""},
+				{org1, "SplitProduct", []string{"s1", `["c1","c2"]`, `[33,33]`}, ""},
+			},
+			productID: "s1",
+			wantTotal: 17,
+			byStage:   map[string]int64{"manufacturing": 17},
+			records:   1,
+		},
+		{"no emissions", nil, "s1", 0, map[string]int64{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "100", "kg")
+			l.submit(org1, "CreateStockProduct", "s2", "Flour", "CompanyA", "", "Food", "50", "kg")
+			l.run(tt.steps)
+
+			var footprint ProductFootprint
+			l.evaluate(org1, &footprint, "GetProductFootprint", tt.productID)
+			if footprint.TotalGramsCO2e != tt.wantTotal {
+				t.Errorf("total = %d gCO2e, want %d", footprint.TotalGramsCO2e, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(footprint.ByStage, tt.byStage) {
+				t.Errorf("by stage = %v, want %v", footprint.ByStage, tt.byStage)
+			}
+			if len(footprint.Records) != tt.records {
+				t.Errorf("footprint has %d records, want %d", len(footprint.Records), tt.records)
+			}
+		})
+	}
+}
+
+func TestAllocateGrams(t *testing.T) {
+	tests := []struct {
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{30, []int64{100, 50}, []int64{20, 10}},
+		{10, []int64{100, 50}, []int64{7, 3}},
+		{50, []int64{33, 33, 34}, []int64{17, 16, 17}},
+		{1, []int64{1, 1, 1}, []int64{1, 0, 0}},
+		{5, []int64{2, 0, 3}, []int64{2, 0, 3}},
+		{1 << 62, []int64{1 << 40, 1 << 40}, []int64{1 << 61, 1 << 61}},
+	}
+
+	for _, tt := range tests {
+		if got := allocateGrams(tt.total, tt.weights); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("allocateGrams(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const categoryObjectType = "Category"
+
+type Category struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Schema      string    `json:"schema"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// attributeSchema is the subset of JSON Schema supported for category
+// attributes. Attribute values are stored as strings, so property types
+// describe how the string must parse.
+type attributeSchema struct {
+	Type                 string                    `json:"type"`
+	Properties           map[string]propertySchema `json:"properties"`
+	Required             []string                  `json:"required"`
+	AdditionalProperties *bool                     `json:"additionalProperties"`
+}
+
+type propertySchema struct {
+	Type    string        `json:"type"`
+	Enum    []interface{} `json:"enum"`
+	Minimum *float64      `json:"minimum"`
+	Maximum *float64      `json:"maximum"`
+	Pattern string        `json:"pattern"`
+}
+
+func (s *SupplyChainContract) RegisterCategory(ctx contractapi.TransactionContextInterface, name, description, schema string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if _, err := parseAttributeSchema(schema); err != nil {
+		return err
+	}
+
+	exists, err := s.CategoryExists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("category %s already exists", name)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	category := Category{
+		Name:        name,
+		Description: description,
+		Schema:      schema,
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
+	}
+
+	err = s.putCategory(ctx, &category)
+	if err != nil {
+		return fmt.Errorf("failed to put category into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateCategorySchema replaces a category's schema. The change is rejected
+// if any existing product in the category does not satisfy the new schema.
+func (s *SupplyChainContract) UpdateCategorySchema(ctx contractapi.TransactionContextInterface, name, description, schema string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	parsed, err := parseAttributeSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	category, err := s.QueryCategory(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.revalidateCategoryProducts(ctx, name, parsed); err != nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	category.Description = description
+	category.Schema = schema
+	category.UpdatedAt = txTime
+
+	err = s.putCategory(ctx, category)
+	if err != nil {
+		return fmt.Errorf("failed to update category: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) QueryCategory(ctx contractapi.TransactionContextInterface, name string) (*Category, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(categoryObjectType, []string{name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	categoryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read category from ledger: %v", err)
+	}
+	if categoryJSON == nil {
+		return nil, fmt.Errorf("the category %s does not exist", name)
+	}
+
+	var category Category
+	err = json.Unmarshal(categoryJSON, &category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal category JSON: %v", err)
+	}
+
+	return &category, nil
+}
+
+func (s *SupplyChainContract) CategoryExists(ctx contractapi.TransactionContextInterface, name string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(categoryObjectType, []string{name})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	categoryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return categoryJSON != nil, nil
+}
+
+func (s *SupplyChainContract) GetAllCategories(ctx contractapi.TransactionContextInterface) ([]*Category, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(categoryObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var categories []*Category
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var category Category
+		if err := json.Unmarshal(queryResponse.Value, &category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	return categories, nil
+}
+
+func (s *SupplyChainContract) SetProductAttributes(ctx contractapi.TransactionContextInterface, id string, attributes map[string]string) error {
+	product, err := s.readProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "owner"); err != nil {
+		return err
+	}
+	if err := s.validateAttributes(ctx, product.Category, attributes); err != nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	product.Attributes = attributes
+	product.UpdatedAt = txTime
+
+	err = s.putProduct(ctx, product)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %v", err)
+	}
+
+	return nil
+}
+
+// validateAttributes checks attributes against the category's schema.
+// Products in unregistered categories may not carry custom attributes.
+func (s *SupplyChainContract) validateAttributes(ctx contractapi.TransactionContextInterface, categoryName string, attributes map[string]string) error {
+	exists, err := s.CategoryExists(ctx, categoryName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if len(attributes) > 0 {
+			return fmt.Errorf("category %s is not registered and does not accept custom attributes", categoryName)
+		}
+		return nil
+	}
+
+	category, err := s.QueryCategory(ctx, categoryName)
+	if err != nil {
+		return err
+	}
+	schema, err := parseAttributeSchema(category.Schema)
+	if err != nil {
+		return fmt.Errorf("category %s has an invalid schema: %v", categoryName, err)
+	}
+
+	return schema.validate(categoryName, attributes)
+}
+
+// revalidateCategoryProducts checks every product in a category against
+// schema, reporting the first product that does not satisfy it.
+func (s *SupplyChainContract) revalidateCategoryProducts(ctx contractapi.TransactionContextInterface, categoryName string, schema *attributeSchema) error {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		product, err := unmarshalProduct(queryResponse.Value)
+		if err != nil {
+			return err
+		}
+		if product.Category != categoryName {
+			continue
+		}
+		if err := schema.validate(categoryName, product.Attributes); err != nil {
+			return fmt.Errorf("product %s does not satisfy the new schema: %v", product.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks attributes against the schema of category categoryName.
+func (schema *attributeSchema) validate(categoryName string, attributes map[string]string) error {
+	for _, name := range schema.Required {
+		if _, ok := attributes[name]; !ok {
+			return fmt.Errorf("attribute %s is required for category %s", name, categoryName)
+		}
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				return fmt.Errorf("attribute %s is not defined for category %s", name, categoryName)
+			}
+			continue
+		}
+		if err := property.validate(attributes[name]); err != nil {
+			return fmt.Errorf("attribute %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+func parseAttributeSchema(schema string) (*attributeSchema, error) {
+	var parsed attributeSchema
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse attribute schema: %v", err)
+	}
+	if parsed.Type != "" && parsed.Type != "object" {
+		return nil, fmt.Errorf("attribute schema must be of type object, got %q", parsed.Type)
+	}
+	for name, property := range parsed.Properties {
+		switch property.Type {
+		case "", "string", "number", "integer", "boolean":
+		default:
+			return nil, fmt.Errorf("attribute %s has unsupported type %q", name, property.Type)
+		}
+		if property.Pattern != "" {
+			if _, err := regexp.Compile(property.Pattern); err != nil {
+				return nil, fmt.Errorf("attribute %s has invalid pattern: %v", name, err)
+			}
+		}
+	}
+	return &parsed, nil
+}
+
+func (p propertySchema) validate(value string) error {
+	var number float64
+	var err error
+	switch p.Type {
+	case "number":
+		number, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+	case "integer":
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		number = float64(n)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	}
+
+	if p.Type == "number" || p.Type == "integer" {
+		if p.Minimum != nil && number < *p.Minimum {
+			return fmt.Errorf("%s is below the minimum %v", value, *p.Minimum)
+		}
+		if p.Maximum != nil && number > *p.Maximum {
+			return fmt.Errorf("%s is above the maximum %v", value, *p.Maximum)
+		}
+	}
+
+	if p.Pattern != "" {
+		matched, err := regexp.MatchString(p.Pattern, value)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("%q does not match pattern %s", value, p.Pattern)
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		for _, allowed := range p.Enum {
+			if fmt.Sprint(allowed) == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %v", value, p.Enum)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) putCategory(ctx contractapi.TransactionContextInterface, category *Category) error {
+	key, err := ctx.GetStub().CreateCompositeKey(categoryObjectType, []string{category.Name})
+	if err != nil {
+		return err
+	}
+
+	categoryJSON, err := marshalState(category)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, categoryJSON)
+}
@@ -0,0 +1,126 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+// wineSchema requires a vintage year and allows an optional colour.
+const wineSchema = `{
+	"type": "object",
+	"properties": {
+		"vintage": {"type": "integer", "minimum": 1900, "maximum": 2100},
+		"colour": {"type": "string", "enum": ["red", "white", "rose"]}
+	},
+	"required": ["vintage"],
+	"additionalProperties": false
+}`
+
+func TestRegisterCategory(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		category  string
+		schema    string
+		wantErr   string
+	}{
+		{"admin", admin, "Wine", wineSchema, ""},
+		{"not admin", org1, "Wine", wineSchema, "admin role required"},
+		{"malformed schema", admin, "Wine", `{"type":`, "failed to parse attribute schema"},
+		{"not an object", admin, "Wine", `{"type":"array"}`, "must be of type object"},
+		{"unsupported type", admin, "Wine", `{"properties":{"vintage":{"type":"date"}}}`, `unsupported type "date"`},
+		{"bad pattern", admin, "Wine", `{"properties":{"code":{"pattern":"("}}}`, "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "RegisterCategory", tt.category, "Wines", tt.schema)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+
+	l := newTestLedger(t)
+	l.submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+	_, err := l.ledger.Submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+	expectError(t, err, "category Wine already exists")
+}
+
+func TestUpdateCategorySchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{"still satisfied", `{"properties":{"vintage":{"type":"integer","minimum":2000}},"required":["vintage"]}`, ""},
+		{"newly required attribute", `{"properties":{"vintage":{"type":"integer"},"region":{"type":"string"}},"required":["vintage","region"]}`, "product p3 does not satisfy the new schema: attribute region is required"},
+		{"narrowed range", `{"properties":{"vintage":{"type":"integer","minimum":2016}},"required":["vintage"]}`, "product p3 does not satisfy the new schema: attribute vintage: 2015 is below the minimum 2016"},
+		{"removed attribute", `{"properties":{"colour":{"type":"string"}},"additionalProperties":false}`, "product p3 does not satisfy the new schema: attribute vintage is not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+			l.submit(org1, "CreateProductWithAttributes", "p3", "Bottle", "CompanyA", "", "Wine", `{"vintage":"2015"}`)
+
+			_, err := l.ledger.Submit(admin, "UpdateCategorySchema", "Wine", "Wines", tt.schema)
+			expectError(t, err, tt.wantErr)
+
+			var category Category
+			l.evaluate(org1, &category, "QueryCategory", "Wine")
+			want := wineSchema
+			if tt.wantErr == "" {
+				want = tt.schema
+			}
+			if category.Schema != want {
+				t.Errorf("schema = %s, want %s", category.Schema, want)
+			}
+		})
+	}
+}
+
+func TestProductAttributes(t *testing.T) {
+	tests := []struct {
+		name       string
+		category   string
+		attributes string
+		wantErr    string
+	}{
+		{"valid", "Wine", `{"vintage":"2015","colour":"red"}`, ""},
+		{"missing required", "Wine", `{"colour":"red"}`, "attribute vintage is required"},
+		{"not an integer", "Wine", `{"vintage":"old"}`, `"old" is not an integer`},
+		{"below minimum", "Wine", `{"vintage":"1800"}`, "below the minimum"},
+		{"not in enum", "Wine", `{"vintage":"2015","colour":"blue"}`, `"blue" is not one of`},
+		{"undefined attribute", "Wine", `{"vintage":"2015","region":"Rioja"}`, "attribute region is not defined"},
+		{"unregistered category", "Electronics", `{"colour":"red"}`, "does not accept custom attributes"},
+		{"unregistered category without attributes", "Electronics", `{}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+
+			_, err := l.ledger.Submit(org1, "CreateProductWithAttributes", "p3", "Bottle", "CompanyA", "", tt.category, tt.attributes)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSetProductAttributes(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+	l.submit(org1, "CreateProductWithAttributes", "p3", "Bottle", "CompanyA", "", "Wine", `{"vintage":"2015"}`)
+
+	l.run([]txStep{
+		{org2, "SetProductAttributes", []string{"p3", `{"vintage":"2016"}`}, "submitter Org2MSP is not authorized to act as owner CompanyA"},
+		{org1, "SetProductAttributes", []string{"p3", `{"colour":"red"}`}, "attribute vintage is required"},
+		{org1, "SetProductAttributes", []string{"p3", `{"vintage":"2016","colour":"white"}`}, ""},
+	})
+
+	if attributes := l.product("p3").Attributes; attributes["vintage"] != "2016" || attributes["colour"] != "white" {
+		t.Errorf("attributes = %v, want vintage 2016 and colour white", attributes)
+	}
+}
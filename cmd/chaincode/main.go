@@ -0,0 +1,35 @@
+// Command chaincode runs the supply chain contract as Fabric chaincode.
+//
+// This is the chaincode's packaging path. Package it from the repository
+// root, which holds go.mod, naming this directory as the main package:
+//
+//	peer lifecycle chaincode package supplychain.tar.gz --lang golang \
+//		--path ./cmd/chaincode --label supplychain_1
+//
+// Set SUPPLYCHAIN_LOG_ARGUMENTS=true in the chaincode's environment to log
+// each transaction's arguments; by default only its name and submitter are
+// logged.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Joeychen80627/smartcontract"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func main() {
+	contract := smartcontract.NewContract()
+	contract.LogArguments = os.Getenv("SUPPLYCHAIN_LOG_ARGUMENTS") == "true"
+
+	chaincode, err := contractapi.NewChaincode(contract)
+	if err != nil {
+		fmt.Printf("Error creating supply chain chaincode: %s", err.Error())
+		return
+	}
+
+	if err := chaincode.Start(); err != nil {
+		fmt.Printf("Error starting supply chain chaincode: %s", err.Error())
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Joeychen80627/smartcontract/internal/backend"
+)
+
+//go:embed openapi.yaml
+var openAPIDocument []byte
+
+const maxRequestBytes = 1 << 20
+
+type createProductRequest struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Owner       string            `json:"owner"`
+	Description string            `json:"description"`
+	Category    string            `json:"category"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+type updateProductRequest struct {
+	Status      string `json:"status"`
+	Owner       string `json:"owner"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+type transferRequest struct {
+	NewOwner string `json:"new_owner"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// badRequest is an error in the HTTP request itself, before it reaches the
+// contract.
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+type handler struct {
+	backend backend.Backend
+}
+
+func newHandler(b backend.Backend) http.Handler {
+	h := &handler{backend: b}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/products", h.products)
+	mux.HandleFunc("/products/", h.product)
+	mux.HandleFunc("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPIDocument)
+	})
+	return mux
+}
+
+// backendFor returns the backend to run the request's transactions on. The
+// in-process backend honours X-MSP-ID and X-Role so local tests can act as
+// any organization; against Fabric the gateway's own identity is used.
+func (h *handler) backendFor(r *http.Request) backend.Backend {
+	inProcess, ok := h.backend.(*backend.InProcess)
+	if !ok || r.Header.Get("X-MSP-ID") == "" {
+		return h.backend
+	}
+	return inProcess.As(r.Header.Get("X-MSP-ID"), r.Header.Get("X-Role"))
+}
+
+// products serves /products.
+func (h *handler) products(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listProducts(w, r)
+	case http.MethodPost:
+		h.createProduct(w, r)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// product serves /products/{id}, /products/{id}/transfer and
+// /products/{id}/history.
+func (h *handler) product(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/products/"), "/")
+	id, err := url.PathUnescape(parts[0])
+	if err != nil || id == "" || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.evaluate(w, r, http.StatusOK, "QueryProduct", id)
+	case action == "" && r.Method == http.MethodPut:
+		h.updateProduct(w, r, id)
+	case action == "":
+		methodNotAllowed(w, http.MethodGet, http.MethodPut)
+	case action == "transfer" && r.Method == http.MethodPost:
+		h.transferProduct(w, r, id)
+	case action == "transfer":
+		methodNotAllowed(w, http.MethodPost)
+	case action == "history" && r.Method == http.MethodGet:
+		h.evaluate(w, r, http.StatusOK, "GetProductHistory", id)
+	case action == "history":
+		methodNotAllowed(w, http.MethodGet)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// listProducts returns every product, or one page of them when page_size
+// or bookmark is given.
+func (h *handler) listProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !query.Has("page_size") && !query.Has("bookmark") {
+		h.evaluate(w, r, http.StatusOK, "GetAllProducts")
+		return
+	}
+
+	pageSize := query.Get("page_size")
+	if pageSize == "" {
+		pageSize = "0"
+	} else if _, err := strconv.ParseInt(pageSize, 10, 32); err != nil {
+		writeError(w, badRequest(fmt.Sprintf("page_size must be an integer, got %q", pageSize)))
+		return
+	}
+	h.evaluate(w, r, http.StatusOK, "GetProductsWithPagination", pageSize, query.Get("bookmark"))
+}
+
+func (h *handler) createProduct(w http.ResponseWriter, r *http.Request) {
+	var req createProductRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.ID == "" {
+		writeError(w, badRequest("id is required"))
+		return
+	}
+
+	b := h.backendFor(r)
+	var err error
+	if len(req.Attributes) > 0 {
+		attributes, marshalErr := json.Marshal(req.Attributes)
+		if marshalErr != nil {
+			writeError(w, marshalErr)
+			return
+		}
+		_, err = b.Submit("CreateProductWithAttributes", req.ID, req.Name, req.Owner, req.Description, req.Category, string(attributes))
+	} else {
+		_, err = b.Submit("CreateProduct", req.ID, req.Name, req.Owner, req.Description, req.Category)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/products/"+url.PathEscape(req.ID))
+	h.evaluate(w, r, http.StatusCreated, "QueryProduct", req.ID)
+}
+
+func (h *handler) updateProduct(w http.ResponseWriter, r *http.Request, id string) {
+	var req updateProductRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if _, err := h.backendFor(r).Submit("UpdateProduct", id, req.Status, req.Owner, req.Description, req.Category); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.evaluate(w, r, http.StatusOK, "QueryProduct", id)
+}
+
+func (h *handler) transferProduct(w http.ResponseWriter, r *http.Request, id string) {
+	var req transferRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.NewOwner == "" {
+		writeError(w, badRequest("new_owner is required"))
+		return
+	}
+
+	if _, err := h.backendFor(r).Submit("TransferOwnership", id, req.NewOwner); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.evaluate(w, r, http.StatusOK, "QueryProduct", id)
+}
+
+// evaluate runs a query and writes its JSON result. Contract functions
+// returning an empty slice produce no payload or null, written as [].
+func (h *handler) evaluate(w http.ResponseWriter, r *http.Request, status int, function string, args ...string) {
+	payload, err := h.backendFor(r).Evaluate(function, args...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(payload) == 0 || string(payload) == "null" {
+		payload = []byte("[]")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload)
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return badRequest(fmt.Sprintf("invalid request body: %v", err))
+	}
+	return nil
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := statusFor(err)
+	if status == http.StatusBadGateway || status == http.StatusInternalServerError {
+		log.Printf("request failed: %v", err)
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusRules map fragments of the contract's error messages to HTTP
+// statuses. They are checked in order; messages matching none are treated
+// as invalid requests.
+var statusRules = []struct {
+	fragment string
+	status   int
+}{
+	{"does not exist", http.StatusNotFound},
+	{"is not a registered participant", http.StatusNotFound},
+	{"no product is identified by", http.StatusNotFound},
+	{"not authorized", http.StatusForbidden},
+	{"failed to authenticate", http.StatusForbidden},
+	{"is only visible to", http.StatusForbidden},
+	{"is not an approver", http.StatusForbidden},
+	{"only the owner", http.StatusForbidden},
+	{"already exists", http.StatusConflict},
+	{"is already", http.StatusConflict},
+	{"has already", http.StatusConflict},
+	{"is frozen", http.StatusConflict},
+	{"is paused", http.StatusConflict},
+	{"requires approval", http.StatusConflict},
+	{"failed to commit", http.StatusConflict},
+	{"cannot be", http.StatusConflict},
+	{"cannot accept", http.StatusConflict},
+	{"checked in at", http.StatusConflict},
+	{"is no longer owned", http.StatusConflict},
+	{"is Suspended", http.StatusConflict},
+}
+
+func statusFor(err error) int {
+	var bad badRequest
+	if errors.As(err, &bad) {
+		return http.StatusBadRequest
+	}
+
+	var contractErr *backend.ContractError
+	if !errors.As(err, &contractErr) {
+		return http.StatusBadGateway
+	}
+	for _, rule := range statusRules {
+		if strings.Contains(contractErr.Message, rule.fragment) {
+			return rule.status
+		}
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/backend"
+)
+
+func TestHandler(t *testing.T) {
+	b, err := backend.NewInProcess("Org1MSP", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newHandler(b))
+	defer server.Close()
+
+	// Requests run in order against the same ledger.
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		mspID      string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"list", http.MethodGet, "/products", "", "", http.StatusOK, `"id":"p1"`},
+		{"page", http.MethodGet, "/products?page_size=1", "", "", http.StatusOK, `"bookmark":"p2"`},
+		{"bad page size", http.MethodGet, "/products?page_size=x", "", "", http.StatusBadRequest, "page_size must be an integer"},
+		{"get", http.MethodGet, "/products/p1", "", "", http.StatusOK, `"owner":"CompanyA"`},
+		{"get missing", http.MethodGet, "/products/nope", "", "", http.StatusNotFound, "does not exist"},
+		{"create", http.MethodPost, "/products", "", `{"id":"p3","name":"Tablet","owner":"CompanyA","category":"Electronics"}`, http.StatusCreated, `"id":"p3"`},
+		{"create duplicate", http.MethodPost, "/products", "", `{"id":"p3","name":"Tablet","owner":"CompanyA","category":"Electronics"}`, http.StatusConflict, "already exists"},
+		{"create unknown field", http.MethodPost, "/products", "", `{"id":"p4","colour":"red"}`, http.StatusBadRequest, "unknown field"},
+		{"create unregistered owner", http.MethodPost, "/products", "", `{"id":"p4","name":"Tablet","owner":"Nobody","category":"Electronics"}`, http.StatusNotFound, "is not a registered participant"},
+		{"transfer", http.MethodPost, "/products/p3/transfer", "", `{"new_owner":"CompanyB"}`, http.StatusOK, `"owner":"CompanyB"`},
+		{"transfer without owner", http.MethodPost, "/products/p3/transfer", "", `{}`, http.StatusBadRequest, "new_owner is required"},
+		{"public view hides owner", http.MethodGet, "/products/p1", "Org3MSP", "", http.StatusOK, `"owner":""`},
+		{"history", http.MethodGet, "/products/p3/history", "", "", http.StatusOK, `"tx_id"`},
+		{"method not allowed", http.MethodDelete, "/products/p1", "", "", http.StatusMethodNotAllowed, "method not allowed"},
+		{"unknown action", http.MethodGet, "/products/p1/nope", "", "", http.StatusNotFound, ""},
+		{"openapi", http.MethodGet, "/openapi.yaml", "", "", http.StatusOK, "openapi: 3.0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.mspID != "" {
+				req.Header.Set("X-MSP-ID", tt.mspID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body %s does not contain %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{badRequest("bad"), http.StatusBadRequest},
+		{errors.New("connection refused"), http.StatusBadGateway},
+		{&backend.ContractError{Message: "the product with ID x does not exist"}, http.StatusNotFound},
+		{&backend.ContractError{Message: "submitter is not authorized: admin role required"}, http.StatusForbidden},
+		{&backend.ContractError{Message: "product p1 is frozen"}, http.StatusConflict},
+		{&backend.ContractError{Message: "quantity must be positive"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if got := statusFor(tt.err); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestEmptyListIsArray(t *testing.T) {
+	b, err := backend.NewInProcess("Org1MSP", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	newHandler(b).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+
+	var products []json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &products); err != nil || products == nil {
+		t.Errorf("body = %s, want an empty JSON array", rec.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+// Command gateway serves the supply chain contract's product transactions as
+// a JSON REST API, described by the OpenAPI document at /openapi.yaml.
+//
+// In fabric mode it submits through a Fabric Gateway peer as a single client
+// identity. In inprocess mode it runs the contract against an in-memory
+// ledger for local testing; requests there may carry X-MSP-ID and X-Role
+// headers to act as another organization or role.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Joeychen80627/smartcontract/internal/backend"
+)
+
+func main() {
+	listen := flag.String("listen", ":8080", "address to serve HTTP on")
+	mode := flag.String("mode", "fabric", "fabric or inprocess")
+
+	var fabric backend.FabricConfig
+	flag.StringVar(&fabric.PeerEndpoint, "peer-endpoint", "localhost:7051", "gateway peer address")
+	flag.StringVar(&fabric.GatewayPeer, "gateway-peer", "", "TLS server name of the gateway peer, if it differs from the address")
+	flag.StringVar(&fabric.TLSCertPath, "tls-cert", "", "path to the gateway peer's TLS CA certificate")
+	flag.StringVar(&fabric.CertPath, "cert", "", "path to the client certificate")
+	flag.StringVar(&fabric.KeyPath, "key", "", "path to the client private key")
+	flag.StringVar(&fabric.Channel, "channel", "mychannel", "channel name")
+	flag.StringVar(&fabric.Chaincode, "chaincode", "supplychain", "chaincode name")
+	mspID := flag.String("msp-id", "Org1MSP", "MSP ID of the client identity")
+	role := flag.String("role", "", "inprocess mode: role attribute of the default identity, if any")
+	seed := flag.Bool("seed", true, "inprocess mode: run InitLedger at startup")
+	flag.Parse()
+
+	var b backend.Backend
+	var err error
+	switch *mode {
+	case "fabric":
+		fabric.MSPID = *mspID
+		b, err = backend.DialFabric(fabric)
+	case "inprocess":
+		b, err = backend.NewInProcess(*mspID, *role, *seed)
+	default:
+		log.Fatalf("unknown mode %q; use fabric or inprocess", *mode)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           newHandler(b),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("serving %s mode on %s", *mode, *listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Joeychen80627/smartcontract"
+	"github.com/Joeychen80627/smartcontract/internal/backend"
+)
+
+// updateFields are the product fields UpdateProduct sets, in its argument
+// order.
+var updateFields = []string{"status", "owner", "description", "category"}
+
+// errNoChanges is a CSV row for update that changes nothing.
+var errNoChanges = errors.New("row has no changes")
+
+func runCreate(c *cli, args []string) error {
+	fs := newFlagSet("create", "-id ID -name NAME -owner OWNER -category CATEGORY [-description TEXT] [-attr KEY=VALUE]...\n       supplychainctl create -file FILE")
+	id := fs.String("id", "", "product ID")
+	name := fs.String("name", "", "product name")
+	owner := fs.String("owner", "", "ID of the owning participant")
+	description := fs.String("description", "", "product description")
+	category := fs.String("category", "", "product category")
+	attributes := attributeFlag{}
+	fs.Var(attributes, "attr", "category attribute as KEY=VALUE; may be repeated")
+	file := fs.String("file", "", "CSV file with columns id, name, owner, category and optionally description and attr.KEY; - for stdin")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *file != "" {
+		if err := fileOnly(fs, positional); err != nil {
+			return err
+		}
+		rows, err := readCSV(*file, []string{"id", "name", "owner", "category"}, []string{"description"}, []string{"attr."})
+		if err != nil {
+			return err
+		}
+		return c.bulk(rows, func(b backend.Backend, row csvRow) error {
+			attributes := make(map[string]string)
+			for column, value := range row.fields {
+				if strings.HasPrefix(column, "attr.") && value != "" {
+					attributes[strings.TrimPrefix(column, "attr.")] = value
+				}
+			}
+			return createProduct(b, row.fields["id"], row.fields["name"], row.fields["owner"], row.fields["description"], row.fields["category"], attributes)
+		})
+	}
+
+	if err := checkArgs(fs, positional, 0); err != nil {
+		return err
+	}
+	if *id == "" {
+		return usagef(fs, "-id or -file is required")
+	}
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+	if err := createProduct(b, *id, *name, *owner, *description, *category, attributes); err != nil {
+		return err
+	}
+	return c.showProduct(*id)
+}
+
+func runUpdate(c *cli, args []string) error {
+	fs := newFlagSet("update", "[-status STATUS] [-owner OWNER] [-description TEXT] [-category CATEGORY] ID\n       supplychainctl update -file FILE")
+	for _, field := range updateFields {
+		fs.String(field, "", "new "+field+"; unchanged if not given")
+	}
+	file := fs.String("file", "", "CSV file with column id and any of status, owner, description and category; empty cells are left unchanged; - for stdin")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *file != "" {
+		if err := fileOnly(fs, positional); err != nil {
+			return err
+		}
+		rows, err := readCSV(*file, []string{"id"}, updateFields, nil)
+		if err != nil {
+			return err
+		}
+		return c.bulk(rows, func(b backend.Backend, row csvRow) error {
+			changes := make(map[string]string)
+			for _, field := range updateFields {
+				if value := row.fields[field]; value != "" {
+					changes[field] = value
+				}
+			}
+			if len(changes) == 0 {
+				return errNoChanges
+			}
+			return updateProduct(b, row.fields["id"], changes)
+		})
+	}
+
+	if err := checkArgs(fs, positional, 1); err != nil {
+		return err
+	}
+	changes := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		changes[f.Name] = f.Value.String()
+	})
+	if len(changes) == 0 {
+		return usagef(fs, "nothing to update")
+	}
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+	id := positional[0]
+	if err := updateProduct(b, id, changes); err != nil {
+		return err
+	}
+	return c.showProduct(id)
+}
+
+func runTransfer(c *cli, args []string) error {
+	fs := newFlagSet("transfer", "ID NEW_OWNER\n       supplychainctl transfer -file FILE")
+	file := fs.String("file", "", "CSV file with columns id and new_owner; - for stdin")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *file != "" {
+		if err := fileOnly(fs, positional); err != nil {
+			return err
+		}
+		rows, err := readCSV(*file, []string{"id", "new_owner"}, nil, nil)
+		if err != nil {
+			return err
+		}
+		return c.bulk(rows, func(b backend.Backend, row csvRow) error {
+			_, err := b.Submit("TransferOwnership", row.fields["id"], row.fields["new_owner"])
+			return err
+		})
+	}
+
+	if err := checkArgs(fs, positional, 2); err != nil {
+		return err
+	}
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+	if _, err := b.Submit("TransferOwnership", positional[0], positional[1]); err != nil {
+		return err
+	}
+	return c.showProduct(positional[0])
+}
+
+func runGet(c *cli, args []string) error {
+	fs := newFlagSet("get", "ID")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkArgs(fs, positional, 1); err != nil {
+		return err
+	}
+	return c.showProduct(positional[0])
+}
+
+func runList(c *cli, args []string) error {
+	fs := newFlagSet("list", "[-page-size N] [-bookmark BOOKMARK]")
+	pageSize := fs.Int("page-size", 0, "list one page of at most N products; 0 lists all unless -bookmark is given")
+	bookmark := fs.String("bookmark", "", "continue from the bookmark printed with the previous page")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkArgs(fs, positional, 0); err != nil {
+		return err
+	}
+
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+	if *pageSize == 0 && *bookmark == "" {
+		var products []*smartcontract.Product
+		if err := evaluate(b, &products, "GetAllProducts"); err != nil {
+			return err
+		}
+		return c.printer.products(products)
+	}
+
+	var page smartcontract.PaginatedProducts
+	if err := evaluate(b, &page, "GetProductsWithPagination", strconv.Itoa(*pageSize), *bookmark); err != nil {
+		return err
+	}
+	return c.printer.page(&page)
+}
+
+func runHistory(c *cli, args []string) error {
+	fs := newFlagSet("history", "ID")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkArgs(fs, positional, 1); err != nil {
+		return err
+	}
+
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+	var history []*smartcontract.ProductHistoryEntry
+	if err := evaluate(b, &history, "GetProductHistory", positional[0]); err != nil {
+		return err
+	}
+	return c.printer.history(history)
+}
+
+func createProduct(b backend.Backend, id, name, owner, description, category string, attributes map[string]string) error {
+	if len(attributes) == 0 {
+		_, err := b.Submit("CreateProduct", id, name, owner, description, category)
+		return err
+	}
+
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return err
+	}
+	_, err = b.Submit("CreateProductWithAttributes", id, name, owner, description, category, string(attributesJSON))
+	return err
+}
+
+// updateProduct applies changes to a product. UpdateProduct replaces all
+// four fields, so those not being changed are filled in from the product's
+// current state. Only the owner may update a product, and the owner's view
+// of it is complete; a view without the owner means the caller is not it.
+func updateProduct(b backend.Backend, id string, changes map[string]string) error {
+	var product smartcontract.Product
+	if err := evaluate(b, &product, "QueryProduct", id); err != nil {
+		return err
+	}
+	if product.Owner == "" {
+		return fmt.Errorf("product %s can only be updated by its owner", id)
+	}
+
+	fields := map[string]*string{
+		"status":      &product.Status,
+		"owner":       &product.Owner,
+		"description": &product.Description,
+		"category":    &product.Category,
+	}
+	for field, value := range changes {
+		*fields[field] = value
+	}
+
+	_, err := b.Submit("UpdateProduct", id, product.Status, product.Owner, product.Description, product.Category)
+	return err
+}
+
+func (c *cli) showProduct(id string) error {
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+	var product smartcontract.Product
+	if err := evaluate(b, &product, "QueryProduct", id); err != nil {
+		return err
+	}
+	return c.printer.product(&product)
+}
+
+// evaluate runs a query and decodes its JSON result into v. Queries
+// returning an empty slice produce no payload, which leaves v unchanged.
+func evaluate(b backend.Backend, v interface{}, function string, args ...string) error {
+	payload, err := b.Evaluate(function, args...)
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s result: %v", function, err)
+	}
+	return nil
+}
+
+// bulkResult is the outcome of one CSV row.
+type bulkResult struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// bulk runs op for each row in its own transaction and reports the outcome
+// of every row. A row rejected by the contract does not stop the rest; a
+// failure to reach the network does, since every later row would fail too.
+func (c *cli) bulk(rows []csvRow, op func(b backend.Backend, row csvRow) error) error {
+	b, err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	results := make([]bulkResult, 0, len(rows))
+	failed := 0
+	var opErr error
+	for _, row := range rows {
+		result := bulkResult{Line: row.line, ID: row.fields["id"], OK: true}
+		if err := op(b, row); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+			failed++
+			var contractErr *backend.ContractError
+			if !errors.As(err, &contractErr) && !errors.Is(err, errNoChanges) {
+				opErr = err
+			}
+		}
+		results = append(results, result)
+		if opErr != nil {
+			break
+		}
+	}
+
+	if err := c.printer.results(results); err != nil {
+		return err
+	}
+	if opErr != nil {
+		return fmt.Errorf("stopped at line %d of %d rows: %v", results[len(results)-1].Line, len(rows), opErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", failed, len(rows))
+	}
+	return nil
+}
+
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: supplychainctl %s %s\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses a command's flags, which may come before or after its
+// positional arguments, and returns the latter.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func checkArgs(fs *flag.FlagSet, positional []string, nargs int) error {
+	if len(positional) != nargs {
+		return usagef(fs, "expected %d arguments, got %d", nargs, len(positional))
+	}
+	return nil
+}
+
+// fileOnly rejects single-product flags and arguments given alongside -file.
+func fileOnly(fs *flag.FlagSet, positional []string) error {
+	if len(positional) > 0 {
+		return usagef(fs, "-file cannot be combined with arguments")
+	}
+	var others []string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "file" {
+			others = append(others, "-"+f.Name)
+		}
+	})
+	if len(others) > 0 {
+		sort.Strings(others)
+		return usagef(fs, "-file cannot be combined with %s", strings.Join(others, ", "))
+	}
+	return nil
+}
+
+func usagef(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(fs.Output(), "supplychainctl %s: %s\n", fs.Name(), fmt.Sprintf(format, args...))
+	fs.Usage()
+	return errUsage
+}
+
+// attributeFlag collects repeated KEY=VALUE flags.
+type attributeFlag map[string]string
+
+func (a attributeFlag) String() string {
+	return formatAttributes(a)
+}
+
+func (a attributeFlag) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("attribute %q is not KEY=VALUE", pair)
+	}
+	a[key] = value
+	return nil
+}
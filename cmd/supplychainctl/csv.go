@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// csvRow is one record of a CSV file, keyed by column name.
+type csvRow struct {
+	line   int
+	fields map[string]string
+}
+
+// readCSV reads a CSV file whose first record names its columns, or
+// standard input if path is "-". Every required column must be present, and
+// every other column must be optional or start with one of prefixes. Column
+// names are case-insensitive and values are trimmed of surrounding spaces.
+func readCSV(path string, required, optional, prefixes []string) ([]csvRow, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	reader := csv.NewReader(in)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !knownColumn(name, required, optional, prefixes) {
+			return nil, fmt.Errorf("%s: unknown column %q; expected %s", path, name, describeColumns(required, optional, prefixes))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s: column %q appears more than once", path, name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	for _, name := range required {
+		if !seen[name] {
+			return nil, fmt.Errorf("%s: missing column %q", path, name)
+		}
+	}
+
+	var rows []csvRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := csvRow{line: line, fields: make(map[string]string, len(columns))}
+		for i, value := range record {
+			row.fields[columns[i]] = strings.TrimSpace(value)
+		}
+		if row.fields["id"] == "" {
+			return nil, fmt.Errorf("%s:%d: id is empty", path, line)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has no rows", path)
+	}
+
+	return rows, nil
+}
+
+func knownColumn(name string, required, optional, prefixes []string) bool {
+	for _, column := range append(append([]string{}, required...), optional...) {
+		if name == column {
+			return true
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeColumns(required, optional, prefixes []string) string {
+	description := strings.Join(required, ", ")
+	var others []string
+	others = append(others, optional...)
+	for _, prefix := range prefixes {
+		others = append(others, prefix+"KEY")
+	}
+	if len(others) > 0 {
+		description += " and optionally " + strings.Join(others, ", ")
+	}
+	return description
+}
@@ -0,0 +1,143 @@
+// Command supplychainctl runs the supply chain contract's product
+// transactions from the command line, so operators do not have to invoke
+// them as peer chaincode commands with hand-written JSON arguments.
+//
+// Usage:
+//
+//	supplychainctl [flags] <command> [command flags] [args]
+//
+// The create, update and transfer commands take either a single product on
+// the command line or a CSV file with one product per row. Results are
+// printed as a table, or as JSON with -output json.
+//
+// By default commands are submitted through a Fabric Gateway peer. With
+// -local they run against an in-memory ledger seeded by InitLedger instead,
+// so a command or CSV file can be tried out without touching the network.
+// Nothing is kept after the command exits.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Joeychen80627/smartcontract/internal/backend"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"create", "create a product, or one per row of a CSV file", runCreate},
+	{"update", "update the status, owner, description or category of products", runUpdate},
+	{"transfer", "transfer ownership of products", runTransfer},
+	{"get", "show a product", runGet},
+	{"list", "list products, optionally a page at a time", runList},
+	{"history", "show every version of a product", runHistory},
+}
+
+// errUsage reports invalid command-line arguments whose usage message has
+// already been printed.
+var errUsage = errors.New("invalid usage")
+
+// cli holds what every command needs. The backend is connected on first use
+// so that usage errors are reported without dialing the network.
+type cli struct {
+	connectBackend func() (backend.Backend, error)
+	backend        backend.Backend
+	printer        *printer
+}
+
+func (c *cli) connect() (backend.Backend, error) {
+	if c.backend == nil {
+		b, err := c.connectBackend()
+		if err != nil {
+			return nil, err
+		}
+		c.backend = b
+	}
+	return c.backend, nil
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	flag.Usage = usage
+	local := flag.Bool("local", false, "run against an in-memory ledger instead of Fabric; nothing is persisted")
+	output := flag.String("output", "table", "output format: table or json")
+
+	var fabric backend.FabricConfig
+	flag.StringVar(&fabric.PeerEndpoint, "peer-endpoint", "localhost:7051", "gateway peer address")
+	flag.StringVar(&fabric.GatewayPeer, "gateway-peer", "", "TLS server name of the gateway peer, if it differs from the address")
+	flag.StringVar(&fabric.TLSCertPath, "tls-cert", "", "path to the gateway peer's TLS CA certificate")
+	flag.StringVar(&fabric.CertPath, "cert", "", "path to the client certificate")
+	flag.StringVar(&fabric.KeyPath, "key", "", "path to the client private key")
+	flag.StringVar(&fabric.Channel, "channel", "mychannel", "channel name")
+	flag.StringVar(&fabric.Chaincode, "chaincode", "supplychain", "chaincode name")
+	mspID := flag.String("msp-id", "Org1MSP", "MSP ID of the client identity")
+	role := flag.String("role", "", "local mode: role attribute of the client identity, if any")
+	seed := flag.Bool("seed", true, "local mode: run InitLedger before the command")
+	flag.Parse()
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "supplychainctl: unknown output format %q; use table or json\n", *output)
+		return 2
+	}
+	if flag.NArg() == 0 {
+		usage()
+		return 2
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == flag.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "supplychainctl: unknown command %q\n", flag.Arg(0))
+		usage()
+		return 2
+	}
+
+	c := &cli{printer: &printer{out: os.Stdout, format: *output}}
+	c.connectBackend = func() (backend.Backend, error) {
+		if *local {
+			fmt.Fprintln(os.Stderr, "local mode: running against an in-memory ledger; changes are discarded on exit")
+			return backend.NewInProcess(*mspID, *role, *seed)
+		}
+		fabric.MSPID = *mspID
+		return backend.DialFabric(fabric)
+	}
+
+	err := cmd.run(c, flag.Args()[1:])
+	if c.backend != nil {
+		c.backend.Close()
+	}
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "supplychainctl: %v\n", err)
+	return 1
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: supplychainctl [flags] <command> [command flags] [args]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s%s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nRun supplychainctl <command> -h for a command's flags.\n\nflags:\n")
+	flag.PrintDefaults()
+}
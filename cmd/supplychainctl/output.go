@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Joeychen80627/smartcontract"
+)
+
+// printer writes command results to out as aligned tables or indented JSON.
+// Fields the caller's view of a product leaves empty are shown blank.
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func (p *printer) json(v interface{}) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// table writes rows under a header, separated by tabs.
+func (p *printer) table(header string, rows [][]string) error {
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, header)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (p *printer) product(product *smartcontract.Product) error {
+	if p.format == "json" {
+		return p.json(product)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", name, value)
+		}
+	}
+	field("ID", product.ID)
+	field("Name", product.Name)
+	field("Status", product.Status)
+	field("Owner", product.Owner)
+	field("Category", product.Category)
+	field("Description", product.Description)
+	if product.Unit != "" {
+		field("Quantity", fmt.Sprintf("%d %s", product.Quantity, product.Unit))
+	}
+	field("Location", product.Location)
+	field("GTIN", product.GTIN)
+	field("Serial", product.Serial)
+	field("EPC", product.EPC)
+	field("Parents", strings.Join(product.Parents, ", "))
+	field("Children", strings.Join(product.Children, ", "))
+	field("Attributes", formatAttributes(product.Attributes))
+	field("Created", formatTime(product.CreatedAt))
+	field("Updated", formatTime(product.UpdatedAt))
+	return w.Flush()
+}
+
+func (p *printer) products(products []*smartcontract.Product) error {
+	if p.format == "json" {
+		if products == nil {
+			products = []*smartcontract.Product{}
+		}
+		return p.json(products)
+	}
+
+	rows := make([][]string, 0, len(products))
+	for _, product := range products {
+		rows = append(rows, []string{product.ID, product.Name, product.Status, product.Owner, product.Category, product.Location, formatTime(product.UpdatedAt)})
+	}
+	return p.table("ID\tNAME\tSTATUS\tOWNER\tCATEGORY\tLOCATION\tUPDATED", rows)
+}
+
+// page writes one page of products. In table form the bookmark for the next
+// page goes to standard error so that standard output holds only the table.
+func (p *printer) page(page *smartcontract.PaginatedProducts) error {
+	if p.format == "json" {
+		if page.Records == nil {
+			page.Records = []*smartcontract.Product{}
+		}
+		return p.json(page)
+	}
+
+	if err := p.products(page.Records); err != nil {
+		return err
+	}
+	if page.Bookmark != "" {
+		fmt.Fprintf(os.Stderr, "next page: -bookmark %s\n", page.Bookmark)
+	}
+	return nil
+}
+
+func (p *printer) history(history []*smartcontract.ProductHistoryEntry) error {
+	if p.format == "json" {
+		if history == nil {
+			history = []*smartcontract.ProductHistoryEntry{}
+		}
+		return p.json(history)
+	}
+
+	rows := make([][]string, 0, len(history))
+	for _, entry := range history {
+		row := []string{formatTime(entry.Timestamp), entry.TxID, "", "", "", ""}
+		if entry.IsDelete {
+			row[2] = "(deleted)"
+		} else if entry.Product != nil {
+			row[2], row[3], row[4], row[5] = entry.Product.Status, entry.Product.Owner, entry.Product.Location, entry.Product.Description
+		}
+		rows = append(rows, row)
+	}
+	return p.table("TIME\tTX ID\tSTATUS\tOWNER\tLOCATION\tDESCRIPTION", rows)
+}
+
+func (p *printer) results(results []bulkResult) error {
+	if p.format == "json" {
+		return p.json(results)
+	}
+
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		outcome := "ok"
+		if !result.OK {
+			outcome = "failed: " + result.Error
+		}
+		rows = append(rows, []string{fmt.Sprint(result.Line), result.ID, outcome})
+	}
+	return p.table("LINE\tID\tRESULT", rows)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatAttributes(attributes map[string]string) string {
+	pairs := make([]string, 0, len(attributes))
+	for key, value := range attributes {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,181 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const configObjectType = "Config"
+
+type ContractConfig struct {
+	Version              int                 `json:"version"`
+	AllowedCategories    []string            `json:"allowed_categories"`
+	DefaultPageSize      int32               `json:"default_page_size"`
+	MaxPageSize          int32               `json:"max_page_size"`
+	MaxAuthenticityScans int32               `json:"max_authenticity_scans"`
+	RoleAssignments      map[string][]string `json:"role_assignments"`
+	UpdatedBy            string              `json:"updated_by,omitempty" metadata:",optional"`
+	UpdatedAt            time.Time           `json:"updated_at" metadata:",optional"`
+}
+
+type ConfigHistoryEntry struct {
+	TxID      string          `json:"tx_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Config    *ContractConfig `json:"config"`
+}
+
+func defaultConfig() *ContractConfig {
+	return &ContractConfig{
+		AllowedCategories:    []string{},
+		DefaultPageSize:      50,
+		MaxPageSize:          500,
+		MaxAuthenticityScans: 3,
+		RoleAssignments:      map[string][]string{},
+	}
+}
+
+// GetConfig returns the on-ledger configuration, or the defaults if an admin
+// has never set one.
+func (s *SupplyChainContract) GetConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"contract"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from ledger: %v", err)
+	}
+	if configJSON == nil {
+		return defaultConfig(), nil
+	}
+
+	config := defaultConfig()
+	err = json.Unmarshal(configJSON, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config JSON: %v", err)
+	}
+
+	return config, nil
+}
+
+// UpdateConfig replaces the configuration and emits a ConfigUpdated event.
+// The version is assigned by the contract.
+func (s *SupplyChainContract) UpdateConfig(ctx contractapi.TransactionContextInterface, config ContractConfig) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if config.DefaultPageSize <= 0 || config.MaxPageSize <= 0 {
+		return fmt.Errorf("page sizes must be positive")
+	}
+	if config.DefaultPageSize > config.MaxPageSize {
+		return fmt.Errorf("default page size %d exceeds max page size %d", config.DefaultPageSize, config.MaxPageSize)
+	}
+	if config.MaxAuthenticityScans <= 0 {
+		return fmt.Errorf("max authenticity scans must be positive")
+	}
+	if config.AllowedCategories == nil {
+		config.AllowedCategories = []string{}
+	}
+	if config.RoleAssignments == nil {
+		config.RoleAssignments = map[string][]string{}
+	}
+
+	current, err := s.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	config.Version = current.Version + 1
+	config.UpdatedBy = submitter
+	config.UpdatedAt = txTime
+
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"contract"})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	configJSON, err := marshalState(config)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(key, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put config into ledger: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent("ConfigUpdated", configJSON)
+}
+
+func (s *SupplyChainContract) GetConfigHistory(ctx contractapi.TransactionContextInterface) ([]*ConfigHistoryEntry, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{"contract"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var history []*ConfigHistoryEntry
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &ConfigHistoryEntry{TxID: modification.TxId}
+		if modification.Timestamp != nil {
+			entry.Timestamp = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
+		if !modification.IsDelete {
+			entry.Config = defaultConfig()
+			if err := json.Unmarshal(modification.Value, entry.Config); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal config JSON: %v", err)
+			}
+		}
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+func (s *SupplyChainContract) validateCategory(ctx contractapi.TransactionContextInterface, category string) error {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if len(config.AllowedCategories) == 0 || containsString(config.AllowedCategories, category) {
+		return nil
+	}
+	return fmt.Errorf("category %q is not allowed; allowed categories are %v", category, config.AllowedCategories)
+}
+
+// pageSize clamps a requested page size to the configured limits, using the
+// default when none is requested.
+func (s *SupplyChainContract) pageSize(ctx contractapi.TransactionContextInterface, requested int32) (int32, error) {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if requested <= 0 {
+		return config.DefaultPageSize, nil
+	}
+	if requested > config.MaxPageSize {
+		return config.MaxPageSize, nil
+	}
+	return requested, nil
+}
@@ -0,0 +1,96 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+// configJSON encodes the default configuration as changed by modify.
+func configJSON(modify func(*ContractConfig)) string {
+	config := defaultConfig()
+	modify(config)
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+	return string(configJSON)
+}
+
+func TestUpdateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		modify    func(*ContractConfig)
+		wantErr   string
+	}{
+		{"admin", admin, func(c *ContractConfig) { c.DefaultPageSize = 10 }, ""},
+		{"not admin", org1, func(c *ContractConfig) { c.DefaultPageSize = 10 }, "admin role required"},
+		{"zero page size", admin, func(c *ContractConfig) { c.DefaultPageSize = 0 }, "page sizes must be positive"},
+		{"default above max", admin, func(c *ContractConfig) { c.DefaultPageSize = 1000 }, "exceeds max page size"},
+		{"no scans", admin, func(c *ContractConfig) { c.MaxAuthenticityScans = 0 }, "max authenticity scans must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "UpdateConfig", configJSON(tt.modify))
+			expectError(t, err, tt.wantErr)
+
+			var config ContractConfig
+			l.evaluate(org1, &config, "GetConfig")
+			wantVersion, wantPageSize := 1, int32(10)
+			if err != nil {
+				wantVersion, wantPageSize = 0, defaultConfig().DefaultPageSize
+			}
+			if config.Version != wantVersion || config.DefaultPageSize != wantPageSize {
+				t.Errorf("config = version %d page size %d, want version %d page size %d", config.Version, config.DefaultPageSize, wantVersion, wantPageSize)
+			}
+		})
+	}
+}
+
+func TestConfigEffects(t *testing.T) {
+	allowElectronics := func(c *ContractConfig) { c.AllowedCategories = []string{"Electronics"} }
+	assignAdmin := func(c *ContractConfig) { c.RoleAssignments = map[string][]string{"admin": {"Org2MSP"}} }
+
+	tests := []struct {
+		name   string
+		modify func(*ContractConfig)
+		step   txStep
+	}{
+		{"allowed category", allowElectronics, txStep{org1, "CreateProduct", []string{"p3", "Tablet", "CompanyA", "", "Electronics"}, ""}},
+		{"disallowed category", allowElectronics, txStep{org1, "CreateProduct", []string{"p3", "Apple", "CompanyA", "", "Food"}, `category "Food" is not allowed`}},
+		{"assigned role", assignAdmin, txStep{org2, "RegisterParticipant", []string{"CompanyC", "Company C", "Org3MSP", "Retailer", ""}, ""}},
+		{"unassigned role", assignAdmin, txStep{org1, "RegisterParticipant", []string{"CompanyC", "Company C", "Org3MSP", "Retailer", ""}, "admin role required"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "UpdateConfig", configJSON(tt.modify))
+
+			l.run([]txStep{tt.step})
+		})
+	}
+}
+
+func TestConfigHistory(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(admin, "UpdateConfig", configJSON(func(c *ContractConfig) { c.DefaultPageSize = 10 }))
+	l.submit(admin, "UpdateConfig", configJSON(func(c *ContractConfig) { c.DefaultPageSize = 20 }))
+
+	var history []*ConfigHistoryEntry
+	l.evaluate(org1, &history, "GetConfigHistory")
+	if len(history) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(history))
+	}
+	versions := map[int]bool{}
+	for _, entry := range history {
+		versions[entry.Config.Version] = true
+	}
+	if !versions[1] || !versions[2] {
+		t.Errorf("history versions = %v, want 1 and 2", versions)
+	}
+}
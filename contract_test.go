@@ -0,0 +1,124 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Identities used across the contract tests. InitLedger registers CompanyA
+// under Org1MSP and CompanyB under Org2MSP; Org3MSP has no participant.
+var (
+	admin   = mockledger.Identity{MSPID: "Org1MSP", Role: "admin"}
+	auditor = mockledger.Identity{MSPID: "Org1MSP", Role: "auditor"}
+	org1    = mockledger.Identity{MSPID: "Org1MSP"}
+	org2    = mockledger.Identity{MSPID: "Org2MSP"}
+	org3    = mockledger.Identity{MSPID: "Org3MSP"}
+)
+
+// testLedger runs the contract on an in-memory ledger seeded by InitLedger
+// with products p1, owned by CompanyA, and p2, owned by CompanyB.
+type testLedger struct {
+	t      *testing.T
+	ledger *mockledger.Ledger
+}
+
+// The chaincode holds no state of its own, and compiling its metadata
+// schemas is slow, so every test ledger shares one instance.
+var testChaincode struct {
+	once      sync.Once
+	chaincode *contractapi.ContractChaincode
+	err       error
+}
+
+func newTestLedger(t *testing.T) *testLedger {
+	t.Helper()
+	l := newEmptyLedger(t)
+	l.submit(admin, "InitLedger")
+	return l
+}
+
+// newEmptyLedger returns a ledger on which InitLedger has not been run.
+func newEmptyLedger(t *testing.T) *testLedger {
+	t.Helper()
+	testChaincode.once.Do(func() {
+		testChaincode.chaincode, testChaincode.err = contractapi.NewChaincode(NewContract())
+	})
+	if testChaincode.err != nil {
+		t.Fatalf("failed to create chaincode: %v", testChaincode.err)
+	}
+	return &testLedger{t: t, ledger: mockledger.New(testChaincode.chaincode)}
+}
+
+// submit runs a transaction that must succeed and returns its payload.
+func (l *testLedger) submit(id mockledger.Identity, function string, args ...string) []byte {
+	l.t.Helper()
+	payload, err := l.ledger.Submit(id, function, args...)
+	if err != nil {
+		l.t.Fatalf("%s(%s) as %s: %v", function, strings.Join(args, ", "), id.MSPID, err)
+	}
+	return payload
+}
+
+// evaluate runs a query that must succeed and decodes its result into v.
+func (l *testLedger) evaluate(id mockledger.Identity, v interface{}, function string, args ...string) {
+	l.t.Helper()
+	payload, err := l.ledger.Evaluate(id, function, args...)
+	if err != nil {
+		l.t.Fatalf("%s(%s) as %s: %v", function, strings.Join(args, ", "), id.MSPID, err)
+	}
+	if len(payload) == 0 {
+		return
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		l.t.Fatalf("failed to decode %s result %s: %v", function, payload, err)
+	}
+}
+
+// product returns a product as an auditor sees it, which is unprojected.
+func (l *testLedger) product(id string) *Product {
+	l.t.Helper()
+	var product Product
+	l.evaluate(auditor, &product, "QueryProduct", id)
+	return &product
+}
+
+// txStep is one transaction in a scripted scenario, with the error it must
+// fail with, or none.
+type txStep struct {
+	submitter mockledger.Identity
+	function  string
+	args      []string
+	wantErr   string
+}
+
+// run submits steps in order, prefixing each one's arguments with prefix.
+func (l *testLedger) run(steps []txStep, prefix ...string) {
+	l.t.Helper()
+	for _, step := range steps {
+		_, err := l.ledger.Submit(step.submitter, step.function, append(append([]string{}, prefix...), step.args...)...)
+		expectError(l.t, err, step.wantErr)
+	}
+}
+
+// expectError checks that err is a contract error mentioning fragment, or
+// nil if fragment is empty.
+func expectError(t *testing.T, err error, fragment string) {
+	t.Helper()
+	if fragment == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected an error mentioning %q, got success", fragment)
+	}
+	if !strings.Contains(err.Error(), fragment) {
+		t.Fatalf("expected an error mentioning %q, got %v", fragment, err)
+	}
+}
@@ -0,0 +1,154 @@
+package smartcontract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// stateObjectTypes are the composite-key object types whose values are
+// encoded with marshalState. Index entries, which hold raw IDs or markers,
+// are not listed.
+var stateObjectTypes = []string{
+	approvalPolicyObjectType, pendingOperationObjectType,
+	auditEntryObjectType, auditHeadObjectType,
+	authTokenObjectType,
+	balanceObjectType,
+	categoryObjectType,
+	configObjectType,
+	custodyEventObjectType,
+	emissionObjectType,
+	escrowObjectType,
+	holdObjectType,
+	invoiceObjectType,
+	locationObjectType,
+	migrationObjectType,
+	participantObjectType,
+	pauseObjectType,
+	purchaseOrderObjectType,
+	returnObjectType,
+	transferObjectType,
+	warrantyTermsObjectType, warrantyObjectType, warrantyClaimObjectType,
+}
+
+type StateEncodingReport struct {
+	Checked      int      `json:"checked"`
+	NonCanonical []string `json:"non_canonical"`
+}
+
+// marshalState encodes a value for the world state in canonical form: object
+// keys sorted bytewise at every level, no insignificant whitespace, no HTML
+// escaping, and numbers exactly as encoding/json writes them. The output does
+// not depend on struct field order, so every endorsing peer writes the same
+// bytes however fields are added or reordered. The value is encoded twice and
+// the results compared, so a nondeterministic custom marshaller fails the
+// transaction on the peer instead of splitting endorsements.
+func marshalState(v interface{}) ([]byte, error) {
+	first, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	second, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(first, second) {
+		return nil, fmt.Errorf("%T does not encode deterministically", v)
+	}
+	return canonicalizeJSON(first)
+}
+
+// canonicalizeJSON rewrites a JSON document in the form marshalState
+// produces. Decoding into generic values sorts object keys on re-encoding,
+// and json.Number keeps numbers byte-for-byte.
+func canonicalizeJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %v", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// VerifyStateEncoding checks that every stored product and asset is in
+// canonical form and lists the keys of those that are not. Records written
+// before canonical encoding was introduced are listed until they are next
+// updated; any other entry points to a write path that bypasses
+// marshalState.
+func (s *SupplyChainContract) VerifyStateEncoding(ctx contractapi.TransactionContextInterface) (*StateEncodingReport, error) {
+	report := &StateEncodingReport{NonCanonical: []string{}}
+
+	check := func(displayKey string, value []byte) error {
+		report.Checked++
+		canonical, err := canonicalizeJSON(value)
+		if err != nil {
+			return fmt.Errorf("record %s: %v", displayKey, err)
+		}
+		if !bytes.Equal(canonical, value) {
+			report.NonCanonical = append(report.NonCanonical, displayKey)
+		}
+		return nil
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if err := check(queryResponse.Key, queryResponse.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, objectType := range stateObjectTypes {
+		if err := s.verifyObjectTypeEncoding(ctx, objectType, check); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *SupplyChainContract) verifyObjectTypeEncoding(ctx contractapi.TransactionContextInterface, objectType string, check func(displayKey string, value []byte) error) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return err
+		}
+		displayKey := objectType + "/" + strings.Join(attributes, "/")
+		if err := check(displayKey, queryResponse.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
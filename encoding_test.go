@@ -0,0 +1,104 @@
+package smartcontract
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalStateIsDeterministic(t *testing.T) {
+	attributes := make(map[string]string)
+	footprint := make(map[string]int64)
+	allocation := make(map[string]int64)
+	for i := 0; i < 50; i++ {
+		attributes[fmt.Sprintf("attribute%02d", i)] = fmt.Sprintf("<value %d & more>", i)
+		allocation[fmt.Sprintf("p%d", i)] = int64(i) * 7
+	}
+	for _, stage := range emissionStages {
+		footprint[stage] = 1500
+	}
+	records := []*EmissionRecord{
+		{ID: "e1", Scope: "product", Target: "p1", Stage: "transport", GramsCO2e: 12500, Allocation: allocation},
+		{ID: "e2", Scope: "shipment", Target: shipmentSSCC, Stage: "storage", GramsCO2e: 1, Allocation: allocation},
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"product with maps", &Product{ID: "p1", Name: "Laptop", Attributes: attributes, Footprint: footprint, Parents: []string{"s1", "s2"}}},
+		{"nested records", &ProductFootprint{ProductID: "p1", ByStage: footprint, Records: records}},
+		{"map of slices", &ContractConfig{Version: 2, RoleAssignments: map[string][]string{"admin": {"Org1MSP"}, "auditor": {"Org2MSP", "Org3MSP"}, "compliance": {}}}},
+		{"generic map", map[string]interface{}{"z": []interface{}{map[string]interface{}{"b": 1, "a": 2}}, "a": attributes}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := marshalState(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 200; i++ {
+				again, err := marshalState(tt.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(again, first) {
+					t.Fatalf("encoding %d differs:\n%s\nwant\n%s", i, again, first)
+				}
+			}
+
+			canonical, err := canonicalizeJSON(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(canonical, first) {
+				t.Errorf("marshalState output is not canonical:\n%s\nwant\n%s", first, canonical)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"sorts keys", `{"b":1,"a":{"d":2,"c":3}}`, `{"a":{"c":3,"d":2},"b":1}`},
+		{"drops whitespace", "{ \"a\" : [ 1, 2 ] }\n", `{"a":[1,2]}`},
+		{"keeps numbers", `{"a":1.50,"b":1e3,"c":12345678901234567890}`, `{"a":1.50,"b":1e3,"c":12345678901234567890}`},
+		{"does not escape HTML", `{"a":"<b> &"}`, `{"a":"<b> &"}`},
+		{"sorts keys bytewise", `{"b":1,"B":2,"a":3}`, `{"B":2,"a":3,"b":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := canonicalizeJSON([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("canonicalizeJSON(%s) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyStateEncoding(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+	l.submit(org1, "CreateProductWithAttributes", "p3", "Bottle", "CompanyA", "", "Wine", `{"vintage":"2015","colour":"red"}`)
+
+	var report StateEncodingReport
+	l.evaluate(auditor, &report, "VerifyStateEncoding")
+	if report.Checked == 0 || len(report.NonCanonical) != 0 {
+		t.Errorf("report = %+v, want every record canonical", report)
+	}
+
+	l.ledger.PutState("legacy", []byte(`{"name": "Bolt", "id": "legacy"}`))
+	l.evaluate(auditor, &report, "VerifyStateEncoding")
+	if len(report.NonCanonical) != 1 || report.NonCanonical[0] != "legacy" {
+		t.Errorf("non-canonical records = %v, want legacy", report.NonCanonical)
+	}
+}
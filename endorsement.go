@@ -0,0 +1,77 @@
+package smartcontract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// GetProductEndorsementPolicy lists the organizations whose peers must endorse
+// changes to the product, as recorded in its key-level endorsement policy.
+func (s *SupplyChainContract) GetProductEndorsementPolicy(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
+	exists, err := s.ProductExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("the product with ID %s does not exist", id)
+	}
+
+	policy, err := ctx.GetStub().GetStateValidationParameter(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endorsement policy for product %s: %v", id, err)
+	}
+	if len(policy) == 0 {
+		return []string{}, nil
+	}
+
+	ep, err := statebased.NewStateEP(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endorsement policy for product %s: %v", id, err)
+	}
+
+	return ep.ListOrgs(), nil
+}
+
+// setProductEndorsement restricts endorsement of the product key to peers of
+// the owning organization. An empty MSP ID is rejected rather than written
+// into a policy no peer can satisfy.
+func (s *SupplyChainContract) setProductEndorsement(ctx contractapi.TransactionContextInterface, id, mspID string) error {
+	if mspID == "" {
+		return fmt.Errorf("cannot set endorsement policy for product %s: owner has no MSP ID", id)
+	}
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	if err := ep.AddOrgs(statebased.RoleTypePeer, mspID); err != nil {
+		return fmt.Errorf("failed to add %s to endorsement policy: %v", mspID, err)
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return fmt.Errorf("failed to build endorsement policy: %v", err)
+	}
+
+	err = ctx.GetStub().SetStateValidationParameter(id, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set endorsement policy for product %s: %v", id, err)
+	}
+
+	return nil
+}
+
+// ownerMSPID resolves a product owner to the MSP ID of its registered
+// participant. Owners that are not registered, or are registered without an
+// MSP ID, are errors: falling back to the owner's name would pin products to
+// an organization that does not exist.
+func (s *SupplyChainContract) ownerMSPID(ctx contractapi.TransactionContextInterface, owner string) (string, error) {
+	participant, err := s.QueryParticipant(ctx, owner)
+	if err != nil {
+		return "", err
+	}
+	if participant.MSPID == "" {
+		return "", fmt.Errorf("participant %s has no MSP ID", owner)
+	}
+	return participant.MSPID, nil
+}
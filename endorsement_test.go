@@ -0,0 +1,60 @@
+package smartcontract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProductEndorsementPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []txStep
+		productID string
+		want      []string
+	}{
+		{"seeded product", nil, "p2", []string{"Org2MSP"}},
+		{
+			name:      "created product",
+			steps:     []txStep{{org1, "CreateProduct", []string{"p3", "Tablet", "CompanyA", "", "Electronics"}, ""}},
+			productID: "p3",
+			want:      []string{"Org1MSP"},
+		},
+		{
+			name:      "follows the owner",
+			steps:     []txStep{{org1, "TransferOwnership", []string{"p1", "CompanyB"}, ""}},
+			productID: "p1",
+			want:      []string{"Org2MSP"},
+		},
+		{
+			name:      "transfer by another organization",
+			steps:     []txStep{{org2, "TransferOwnership", []string{"p1", "CompanyB"}, "submitter Org2MSP is not authorized to act as owner CompanyA"}},
+			productID: "p1",
+			want:      []string{"Org1MSP"},
+		},
+		{
+			name:      "update by another organization",
+			steps:     []txStep{{org2, "UpdateProduct", []string{"p1", "Shipped", "CompanyB", "Laptop", "Electronics"}, "submitter Org2MSP is not authorized to act as owner CompanyA"}},
+			productID: "p1",
+			want:      []string{"Org1MSP"},
+		},
+		{
+			name:      "unregistered owner",
+			steps:     []txStep{{org1, "CreateProduct", []string{"p3", "Tablet", "Org3MSP", "", "Electronics"}, "Org3MSP is not a registered participant"}},
+			productID: "p1",
+			want:      []string{"Org1MSP"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.run(tt.steps)
+
+			var orgs []string
+			l.evaluate(org1, &orgs, "GetProductEndorsementPolicy", tt.productID)
+			if !reflect.DeepEqual(orgs, tt.want) {
+				t.Errorf("endorsing organizations = %v, want %v", orgs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const escrowObjectType = "Escrow"
+
+// Escrow holds tokens for a product sale. Buyer and Seller are participant
+// IDs; the tokens are debited from and credited to the token accounts of
+// their organizations.
+type Escrow struct {
+	ID        string    `json:"id"`
+	ProductID string    `json:"product_id"`
+	Buyer     string    `json:"buyer"`
+	Seller    string    `json:"seller"`
+	Amount    int64     `json:"amount"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateEscrow locks amount tokens from the submitter's organization against
+// a product until the seller settles or the escrow expires. The buyer is the
+// participant of the submitter's organization.
+func (s *SupplyChainContract) CreateEscrow(ctx contractapi.TransactionContextInterface, id, productID string, amount int64, expiresAt string) error {
+	buyer, err := s.submitterParticipant(ctx)
+	if err != nil {
+		return err
+	}
+
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if product.Owner == buyer.ID {
+		return fmt.Errorf("product %s is already owned by %s", productID, buyer.ID)
+	}
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+
+	expiry, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return fmt.Errorf("expiry must be RFC3339: %v", err)
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !expiry.After(txTime) {
+		return fmt.Errorf("expiry %s is not in the future", expiresAt)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(escrowObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	escrowJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if escrowJSON != nil {
+		return fmt.Errorf("escrow with ID %s already exists", id)
+	}
+
+	if err := s.debitTokens(ctx, buyer.MSPID, amount); err != nil {
+		return err
+	}
+
+	escrow := Escrow{
+		ID:        id,
+		ProductID: productID,
+		Buyer:     buyer.ID,
+		Seller:    product.Owner,
+		Amount:    amount,
+		Status:    "Locked",
+		ExpiresAt: expiry,
+		CreatedAt: txTime,
+		UpdatedAt: txTime,
+	}
+
+	err = s.putEscrow(ctx, &escrow)
+	if err != nil {
+		return fmt.Errorf("failed to put escrow into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// SettleEscrow is invoked by the seller: in one transaction it pays the locked
+// tokens to the seller and transfers the product to the buyer.
+func (s *SupplyChainContract) SettleEscrow(ctx contractapi.TransactionContextInterface, id string) error {
+	escrow, err := s.QueryEscrow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if escrow.Status != "Locked" {
+		return fmt.Errorf("escrow %s is %s", id, escrow.Status)
+	}
+
+	if err := s.requireSubmitterActsFor(ctx, escrow.Seller, "seller"); err != nil {
+		return err
+	}
+
+	expired, err := s.escrowExpired(ctx, escrow)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return fmt.Errorf("escrow %s expired at %s", id, escrow.ExpiresAt)
+	}
+
+	product, err := s.readProduct(ctx, escrow.ProductID)
+	if err != nil {
+		return err
+	}
+	if product.Owner != escrow.Seller {
+		return fmt.Errorf("product %s is no longer owned by seller %s", escrow.ProductID, escrow.Seller)
+	}
+
+	sellerMSPID, err := s.ownerMSPID(ctx, escrow.Seller)
+	if err != nil {
+		return err
+	}
+	if err := s.creditTokens(ctx, sellerMSPID, escrow.Amount); err != nil {
+		return err
+	}
+	if err := s.TransferOwnership(ctx, escrow.ProductID, escrow.Buyer); err != nil {
+		return err
+	}
+
+	return s.setEscrowStatus(ctx, escrow, "Released")
+}
+
+// RefundEscrow returns the locked tokens to the buyer once the escrow has
+// expired without settlement.
+func (s *SupplyChainContract) RefundEscrow(ctx contractapi.TransactionContextInterface, id string) error {
+	escrow, err := s.QueryEscrow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if escrow.Status != "Locked" {
+		return fmt.Errorf("escrow %s is %s", id, escrow.Status)
+	}
+
+	expired, err := s.escrowExpired(ctx, escrow)
+	if err != nil {
+		return err
+	}
+	if !expired {
+		return fmt.Errorf("escrow %s does not expire until %s", id, escrow.ExpiresAt)
+	}
+
+	buyerMSPID, err := s.ownerMSPID(ctx, escrow.Buyer)
+	if err != nil {
+		return err
+	}
+	if err := s.creditTokens(ctx, buyerMSPID, escrow.Amount); err != nil {
+		return err
+	}
+
+	return s.setEscrowStatus(ctx, escrow, "Refunded")
+}
+
+func (s *SupplyChainContract) QueryEscrow(ctx contractapi.TransactionContextInterface, id string) (*Escrow, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(escrowObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	escrowJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read escrow from ledger: %v", err)
+	}
+	if escrowJSON == nil {
+		return nil, fmt.Errorf("the escrow with ID %s does not exist", id)
+	}
+
+	var escrow Escrow
+	err = json.Unmarshal(escrowJSON, &escrow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal escrow JSON: %v", err)
+	}
+
+	return &escrow, nil
+}
+
+func (s *SupplyChainContract) escrowExpired(ctx contractapi.TransactionContextInterface, escrow *Escrow) (bool, error) {
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !txTime.Before(escrow.ExpiresAt), nil
+}
+
+func (s *SupplyChainContract) setEscrowStatus(ctx contractapi.TransactionContextInterface, escrow *Escrow, status string) error {
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	escrow.Status = status
+	escrow.UpdatedAt = txTime
+
+	err = s.putEscrow(ctx, escrow)
+	if err != nil {
+		return fmt.Errorf("failed to update escrow: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) putEscrow(ctx contractapi.TransactionContextInterface, escrow *Escrow) error {
+	key, err := ctx.GetStub().CreateCompositeKey(escrowObjectType, []string{escrow.ID})
+	if err != nil {
+		return err
+	}
+
+	escrowJSON, err := marshalState(escrow)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, escrowJSON)
+}
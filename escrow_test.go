@@ -0,0 +1,151 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+func TestCreateEscrow(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		productID string
+		amount    string
+		expiresAt string
+		wantErr   string
+	}{
+		{"buyer locks tokens", org1, "p2", "400", "2099-01-01T00:00:00Z", ""},
+		{"own product", org1, "p1", "400", "2099-01-01T00:00:00Z", "already owned by CompanyA"},
+		{"unregistered submitter", org3, "p2", "400", "2099-01-01T00:00:00Z", "no active participant is registered"},
+		{"insufficient balance", org1, "p2", "1001", "2099-01-01T00:00:00Z", "insufficient balance for Org1MSP"},
+		{"expired", org1, "p2", "400", "2000-01-01T00:00:00Z", "is not in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "MintTokens", "Org1MSP", "1000")
+
+			_, err := l.ledger.Submit(tt.submitter, "CreateEscrow", "e1", tt.productID, tt.amount, tt.expiresAt)
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			var escrow Escrow
+			l.evaluate(org1, &escrow, "QueryEscrow", "e1")
+			if escrow.Buyer != "CompanyA" || escrow.Seller != "CompanyB" || escrow.Status != "Locked" {
+				t.Errorf("escrow = %+v, want CompanyA buying from CompanyB, Locked", escrow)
+			}
+			var account TokenAccount
+			l.evaluate(org1, &account, "GetBalance", "Org1MSP")
+			if account.Balance != 600 {
+				t.Errorf("Org1MSP balance = %d, want 600", account.Balance)
+			}
+		})
+	}
+}
+
+func TestEscrowLifecycle(t *testing.T) {
+	tests := []struct {
+		name        string
+		steps       []txStep
+		wantStatus  string
+		wantOwner   string
+		wantBalance map[string]int64
+	}{
+		{
+			name:        "seller settles",
+			steps:       []txStep{{org2, "SettleEscrow", nil, ""}},
+			wantStatus:  "Released",
+			wantOwner:   "CompanyA",
+			wantBalance: map[string]int64{"Org1MSP": 600, "Org2MSP": 400},
+		},
+		{
+			name:        "buyer cannot settle",
+			steps:       []txStep{{org1, "SettleEscrow", nil, "not authorized to act as seller CompanyB"}},
+			wantStatus:  "Locked",
+			wantOwner:   "CompanyB",
+			wantBalance: map[string]int64{"Org1MSP": 600, "Org2MSP": 0},
+		},
+		{
+			name:        "settled once",
+			steps:       []txStep{{org2, "SettleEscrow", nil, ""}, {org2, "SettleEscrow", nil, "escrow e1 is Released"}},
+			wantStatus:  "Released",
+			wantOwner:   "CompanyA",
+			wantBalance: map[string]int64{"Org1MSP": 600, "Org2MSP": 400},
+		},
+		{
+			name:        "refund before expiry",
+			steps:       []txStep{{org1, "RefundEscrow", nil, "does not expire until"}},
+			wantStatus:  "Locked",
+			wantOwner:   "CompanyB",
+			wantBalance: map[string]int64{"Org1MSP": 600, "Org2MSP": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "MintTokens", "Org1MSP", "1000")
+			l.submit(org1, "CreateEscrow", "e1", "p2", "400", "2099-01-01T00:00:00Z")
+
+			l.run(tt.steps, "e1")
+
+			var escrow Escrow
+			l.evaluate(org1, &escrow, "QueryEscrow", "e1")
+			if escrow.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", escrow.Status, tt.wantStatus)
+			}
+			if owner := l.product("p2").Owner; owner != tt.wantOwner {
+				t.Errorf("p2 owner = %s, want %s", owner, tt.wantOwner)
+			}
+			for org, want := range tt.wantBalance {
+				var account TokenAccount
+				l.evaluate(org1, &account, "GetBalance", org)
+				if account.Balance != want {
+					t.Errorf("%s balance = %d, want %d", org, account.Balance, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRefundExpiredEscrow(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(admin, "MintTokens", "Org1MSP", "1000")
+	l.submit(org1, "CreateEscrow", "e1", "p2", "400", "2099-01-01T00:00:00Z")
+
+	var escrow Escrow
+	l.evaluate(org1, &escrow, "QueryEscrow", "e1")
+	escrow.ExpiresAt = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	escrowJSON, err := json.Marshal(escrow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.ledger.PutState("\x00Escrow\x00e1\x00", escrowJSON)
+
+	l.run([]txStep{
+		{org2, "SettleEscrow", nil, "escrow e1 expired at"},
+		{org1, "RefundEscrow", nil, ""},
+		{org1, "RefundEscrow", nil, "escrow e1 is Refunded"},
+	}, "e1")
+
+	l.evaluate(org1, &escrow, "QueryEscrow", "e1")
+	if escrow.Status != "Refunded" {
+		t.Errorf("status = %s, want Refunded", escrow.Status)
+	}
+	if owner := l.product("p2").Owner; owner != "CompanyB" {
+		t.Errorf("p2 owner = %s, want CompanyB", owner)
+	}
+	for org, want := range map[string]int64{"Org1MSP": 1000, "Org2MSP": 0} {
+		var account TokenAccount
+		l.evaluate(org1, &account, "GetBalance", org)
+		if account.Balance != want {
+			t.Errorf("%s balance = %d, want %d", org, account.Balance, want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const holdObjectType = "Hold"
+
+type Hold struct {
+	Scope      string    `json:"scope"`
+	Target     string    `json:"target"`
+	Reason     string    `json:"reason"`
+	Status     string    `json:"status"`
+	PlacedBy   string    `json:"placed_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReleasedBy string    `json:"released_by,omitempty" metadata:",optional"`
+	ReleasedAt time.Time `json:"released_at" metadata:",optional"`
+}
+
+var holdScopes = []string{"product", "lot", "category", "owner"}
+
+// FreezeAssets places a hold on a product, a lot (a stock product and
+// everything split or merged from it), a category or an owner. Frozen
+// products cannot be changed until the hold is released.
+func (s *SupplyChainContract) FreezeAssets(ctx contractapi.TransactionContextInterface, scope, target, reason string) error {
+	if err := s.requireRole(ctx, "compliance"); err != nil {
+		return err
+	}
+	if !containsString(holdScopes, scope) {
+		return fmt.Errorf("scope must be one of %v, got %q", holdScopes, scope)
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason is required to freeze assets")
+	}
+
+	existing, err := s.queryHold(ctx, scope, target)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Status == "Active" {
+		return fmt.Errorf("%s %s is already frozen: %s", scope, target, existing.Reason)
+	}
+
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	hold := Hold{
+		Scope:     scope,
+		Target:    target,
+		Reason:    reason,
+		Status:    "Active",
+		PlacedBy:  submitter,
+		CreatedAt: txTime,
+	}
+
+	err = s.putHold(ctx, &hold)
+	if err != nil {
+		return fmt.Errorf("failed to put hold into ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) UnfreezeAssets(ctx contractapi.TransactionContextInterface, scope, target string) error {
+	if err := s.requireRole(ctx, "compliance"); err != nil {
+		return err
+	}
+
+	hold, err := s.queryHold(ctx, scope, target)
+	if err != nil {
+		return err
+	}
+	if hold == nil || hold.Status != "Active" {
+		return fmt.Errorf("%s %s is not frozen", scope, target)
+	}
+
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	hold.Status = "Released"
+	hold.ReleasedBy = submitter
+	hold.ReleasedAt = txTime
+
+	err = s.putHold(ctx, hold)
+	if err != nil {
+		return fmt.Errorf("failed to update hold: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) GetActiveHolds(ctx contractapi.TransactionContextInterface) ([]*Hold, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(holdObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var holds []*Hold
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var hold Hold
+		if err := json.Unmarshal(queryResponse.Value, &hold); err != nil {
+			return nil, err
+		}
+		if hold.Status == "Active" {
+			holds = append(holds, &hold)
+		}
+	}
+
+	return holds, nil
+}
+
+// requireNotFrozen rejects a product write, or any other transaction that
+// acts on the product, if either the product as stored or as about to be
+// written is covered by an active hold, so frozen items can neither be
+// changed, sold, returned, claimed on nor moved to or from a frozen owner.
+func (s *SupplyChainContract) requireNotFrozen(ctx contractapi.TransactionContextInterface, product *Product) error {
+	candidates := []*Product{product}
+
+	storedJSON, err := ctx.GetStub().GetState(product.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read product from ledger: %v", err)
+	}
+	if storedJSON != nil {
+		stored, err := unmarshalProduct(storedJSON)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, stored)
+	}
+
+	for _, candidate := range candidates {
+		hold, err := s.activeHoldFor(ctx, candidate, map[string]bool{})
+		if err != nil {
+			return err
+		}
+		if hold != nil {
+			return fmt.Errorf("product %s is frozen by a hold on %s %s: %s", product.ID, hold.Scope, hold.Target, hold.Reason)
+		}
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) activeHoldFor(ctx contractapi.TransactionContextInterface, product *Product, visited map[string]bool) (*Hold, error) {
+	checks := [][2]string{
+		{"product", product.ID},
+		{"lot", product.ID},
+		{"category", product.Category},
+		{"owner", product.Owner},
+	}
+	for _, check := range checks {
+		hold, err := s.queryHold(ctx, check[0], check[1])
+		if err != nil {
+			return nil, err
+		}
+		if hold != nil && hold.Status == "Active" {
+			return hold, nil
+		}
+	}
+
+	// A lot hold also covers everything split or merged from the lot.
+	visited[product.ID] = true
+	for _, parentID := range product.Parents {
+		if visited[parentID] {
+			continue
+		}
+		hold, err := s.lotHoldFor(ctx, parentID, visited)
+		if err != nil || hold != nil {
+			return hold, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *SupplyChainContract) lotHoldFor(ctx contractapi.TransactionContextInterface, id string, visited map[string]bool) (*Hold, error) {
+	visited[id] = true
+
+	hold, err := s.queryHold(ctx, "lot", id)
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil && hold.Status == "Active" {
+		return hold, nil
+	}
+
+	productJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product from ledger: %v", err)
+	}
+	if productJSON == nil {
+		return nil, nil
+	}
+	product, err := unmarshalProduct(productJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, parentID := range product.Parents {
+		if visited[parentID] {
+			continue
+		}
+		hold, err := s.lotHoldFor(ctx, parentID, visited)
+		if err != nil || hold != nil {
+			return hold, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *SupplyChainContract) queryHold(ctx contractapi.TransactionContextInterface, scope, target string) (*Hold, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(holdObjectType, []string{scope, target})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	holdJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hold from ledger: %v", err)
+	}
+	if holdJSON == nil {
+		return nil, nil
+	}
+
+	var hold Hold
+	err = json.Unmarshal(holdJSON, &hold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal hold JSON: %v", err)
+	}
+
+	return &hold, nil
+}
+
+func (s *SupplyChainContract) putHold(ctx contractapi.TransactionContextInterface, hold *Hold) error {
+	key, err := ctx.GetStub().CreateCompositeKey(holdObjectType, []string{hold.Scope, hold.Target})
+	if err != nil {
+		return err
+	}
+
+	holdJSON, err := marshalState(hold)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, holdJSON)
+}
@@ -0,0 +1,115 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+var compliance = mockledger.Identity{MSPID: "Org1MSP", Role: "compliance"}
+
+func TestFreezeAssets(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		scope     string
+		reason    string
+		wantErr   string
+	}{
+		{"compliance officer", compliance, "product", "contamination", ""},
+		{"admin", admin, "product", "contamination", "compliance role required"},
+		{"unknown scope", compliance, "region", "contamination", "scope must be one of"},
+		{"no reason", compliance, "product", "", "a reason is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "FreezeAssets", tt.scope, "p1", tt.reason)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+
+	l := newTestLedger(t)
+	l.submit(compliance, "FreezeAssets", "product", "p1", "contamination")
+	_, err := l.ledger.Submit(compliance, "FreezeAssets", "product", "p1", "again")
+	expectError(t, err, "product p1 is already frozen")
+}
+
+func TestHoldScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		target  string
+		product string
+		wantErr string
+	}{
+		{"product", "product", "p1", "p1", "product p1 is frozen by a hold on product p1"},
+		{"other product", "product", "p2", "p1", ""},
+		{"owner", "owner", "CompanyA", "p1", "frozen by a hold on owner CompanyA"},
+		{"category", "category", "Electronics", "p1", "frozen by a hold on category Electronics"},
+		{"lot covers split children", "lot", "s1", "c1", "product c1 is frozen by a hold on lot s1"},
+		{"lot covers merged products", "lot", "s1", "m1", "product m1 is frozen by a hold on lot s1"},
+		{"other lot", "lot", "s2", "c1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "100", "kg")
+			l.submit(org1, "CreateStockProduct", "s2", "Flour", "CompanyA", "", "Food", "100", "kg")
+			l.submit(org1, "SplitProduct", "s1", `["c1","c2"]`, `[10,10]`)
+			l.submit(org1, "MergeProducts", `["c2","s2"]`, "m1")
+			l.submit(compliance, "FreezeAssets", tt.scope, tt.target, "contamination")
+
+			_, err := l.ledger.Submit(org1, "TransferOwnership", tt.product, "CompanyB")
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFrozenProductTransactions(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		transient map[string][]byte
+		function  string
+		args      []string
+	}{
+		{"escrow", org2, nil, "CreateEscrow", []string{"e1", "p2", "100", "2099-01-01T00:00:00Z"}},
+		{"return", org1, nil, "RequestReturn", []string{"r1", "p2", "faulty"}},
+		{"warranty claim", org1, nil, "SubmitWarrantyClaim", []string{"c1", "p2", "cracked"}},
+		{"proposal", org1, nil, "ProposeOperation", []string{"op1", "TransferOwnership", "p2", `["CompanyB"]`}},
+		{"transfer invoice", org2, nil, "IssueTransferInvoice", []string{"inv1", `["p2"]`, "CompanyA", "100", "EUR", "2099-01-01T00:00:00Z"}},
+		{"authenticity token", org1, authSecret, "GenerateAuthenticityToken", []string{"p2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newSoldLedger(t)
+			l.submit(compliance, "FreezeAssets", "product", "p2", "contamination")
+
+			_, err := l.ledger.SubmitTransient(tt.submitter, tt.transient, tt.function, tt.args...)
+			expectError(t, err, "product p2 is frozen by a hold on product p2")
+		})
+	}
+}
+
+func TestUnfreezeAssets(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(compliance, "FreezeAssets", "owner", "CompanyA", "sanctions review")
+
+	l.run([]txStep{
+		{org1, "TransferOwnership", []string{"p1", "CompanyB"}, "is frozen"},
+		{org1, "UnfreezeAssets", []string{"owner", "CompanyA"}, "compliance role required"},
+		{compliance, "UnfreezeAssets", []string{"owner", "CompanyA"}, ""},
+		{compliance, "UnfreezeAssets", []string{"owner", "CompanyA"}, "owner CompanyA is not frozen"},
+		{org1, "TransferOwnership", []string{"p1", "CompanyB"}, ""},
+	})
+
+	var holds []*Hold
+	l.evaluate(org1, &holds, "GetActiveHolds")
+	if len(holds) != 0 {
+		t.Errorf("active holds = %v, want none", holds)
+	}
+}
@@ -0,0 +1,386 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const epcIndexObjectType = "EPC"
+
+var gs1SerialPattern = regexp.MustCompile(`^[!"%&'()*+,\-./0-9:;<=>?A-Z_a-z]{1,20}$`)
+
+// AssignGS1Identifier gives a product a GTIN and serial number and records the
+// matching SGTIN EPC URI. companyPrefixLength is the length of the GS1 company
+// prefix within the GTIN, which is needed to build the URI.
+func (s *SupplyChainContract) AssignGS1Identifier(ctx contractapi.TransactionContextInterface, id, gtin, serial string, companyPrefixLength int) error {
+	product, err := s.readProduct(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "owner"); err != nil {
+		return err
+	}
+	if product.EPC != "" {
+		return fmt.Errorf("product %s already has GS1 identifier %s", id, product.EPC)
+	}
+
+	gtin, err = normalizeGTIN(gtin)
+	if err != nil {
+		return err
+	}
+	epc, err := sgtinURI(gtin, serial, companyPrefixLength)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(epcIndexObjectType, []string{epc})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("%s is already assigned to product %s", epc, string(existing))
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	product.GTIN = gtin
+	product.Serial = serial
+	product.EPC = epc
+	product.UpdatedAt = txTime
+
+	if err := s.putProduct(ctx, product); err != nil {
+		return fmt.Errorf("failed to update product: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, []byte(id))
+}
+
+func (s *SupplyChainContract) GetProductByEPC(ctx contractapi.TransactionContextInterface, epc string) (*Product, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(epcIndexObjectType, []string{epc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	id, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if id == nil {
+		return nil, fmt.Errorf("no product is identified by %s", epc)
+	}
+
+	return s.QueryProduct(ctx, string(id))
+}
+
+// ValidateGS1Identifier checks a GTIN, SSCC or GLN, including its check digit,
+// and returns it in normalized form.
+func (s *SupplyChainContract) ValidateGS1Identifier(ctx contractapi.TransactionContextInterface, scheme, value string) (string, error) {
+	switch scheme {
+	case "gtin":
+		return normalizeGTIN(value)
+	case "sscc":
+		return value, validateGS1Number("SSCC", value, 18)
+	case "gln":
+		return value, validateGS1Number("GLN", value, 13)
+	}
+	return "", fmt.Errorf("scheme must be gtin, sscc or gln, got %q", scheme)
+}
+
+// GetProductEPCIS renders the product's history as an EPCIS 2.0 JSON-LD
+// document: creation as a commissioning ObjectEvent, ownership changes as
+// TransactionEvents, check-ins and check-outs as arriving and departing
+// ObjectEvents, other changes as observing ObjectEvents and deletion as a
+// decommissioning ObjectEvent. Like the location trail, it is not visible to
+// public callers.
+func (s *SupplyChainContract) GetProductEPCIS(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	product, err := s.readProduct(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return "", err
+	}
+	if viewer.viewOf(product.Owner) == viewPublic {
+		return "", fmt.Errorf("the EPCIS history of product %s is only visible to registered participants", id)
+	}
+	if product.EPC == "" {
+		return "", fmt.Errorf("product %s has no GS1 identifier; assign one with AssignGS1Identifier", id)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to read product history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	type version struct {
+		txID     string
+		at       time.Time
+		product  *Product
+		isDelete bool
+	}
+	var versions []version
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return "", err
+		}
+
+		v := version{txID: modification.TxId, isDelete: modification.IsDelete}
+		if modification.Timestamp != nil {
+			v.at = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
+		if !modification.IsDelete {
+			past, err := unmarshalProduct(modification.Value)
+			if err != nil {
+				return "", err
+			}
+			v.product = past
+		}
+		versions = append(versions, v)
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].at.Before(versions[j].at) })
+
+	locationURIs := make(map[string]string)
+	for _, v := range versions {
+		if v.product == nil || v.product.Location == "" || locationURIs[v.product.Location] != "" {
+			continue
+		}
+		location, err := s.QueryLocation(ctx, v.product.Location)
+		if err != nil {
+			return "", err
+		}
+		locationURIs[location.ID] = locationURI(location)
+	}
+
+	events := []epcisEvent{}
+	var previous *Product
+	for _, v := range versions {
+		event := epcisEvent{
+			EventTime:           v.at.Format(time.RFC3339Nano),
+			EventTimeZoneOffset: "+00:00",
+			EPCList:             []string{product.EPC},
+		}
+
+		switch {
+		case v.isDelete:
+			event.Type = "ObjectEvent"
+			event.Action = "DELETE"
+			event.BizStep = "decommissioning"
+			event.Disposition = "inactive"
+		case previous == nil:
+			event.Type = "ObjectEvent"
+			event.Action = "ADD"
+			event.BizStep = "commissioning"
+			event.Disposition = epcisDisposition(v.product.Status)
+			event.DestinationList = []epcisDestination{{Type: "owning_party", Destination: participantURI(v.product.Owner)}}
+		case previous.Owner != v.product.Owner:
+			event.Type = "TransactionEvent"
+			event.Action = "ADD"
+			event.BizStep = "shipping"
+			event.Disposition = epcisDisposition(v.product.Status)
+			event.BizTransactionList = []epcisBizTransaction{{BizTransaction: "urn:fabric:tx:" + v.txID}}
+			event.SourceList = []epcisSource{{Type: "owning_party", Source: participantURI(previous.Owner)}}
+			event.DestinationList = []epcisDestination{{Type: "owning_party", Destination: participantURI(v.product.Owner)}}
+		case previous.Location != v.product.Location && v.product.Location != "":
+			event.Type = "ObjectEvent"
+			event.Action = "OBSERVE"
+			event.BizStep = "arriving"
+			event.Disposition = epcisDisposition(v.product.Status)
+		case previous.Location != v.product.Location:
+			event.Type = "ObjectEvent"
+			event.Action = "OBSERVE"
+			event.BizStep = "departing"
+			event.Disposition = "in_transit"
+			event.BizLocation = &epcisLocation{ID: locationURIs[previous.Location]}
+		default:
+			event.Type = "ObjectEvent"
+			event.Action = "OBSERVE"
+			event.Disposition = epcisDisposition(v.product.Status)
+		}
+		if v.product != nil && v.product.Location != "" {
+			event.BizLocation = &epcisLocation{ID: locationURIs[v.product.Location]}
+		}
+
+		events = append(events, event)
+		previous = v.product
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	document := epcisDocument{
+		Context:       []string{"https://ref.gs1.org/standards/epcis/2.0.0/epcis-context.jsonld"},
+		Type:          "EPCISDocument",
+		SchemaVersion: "2.0",
+		CreationDate:  txTime.UTC().Format(time.RFC3339Nano),
+		EPCISBody:     epcisBody{EventList: events},
+	}
+
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal EPCIS document: %v", err)
+	}
+	return string(documentJSON), nil
+}
+
+type epcisDocument struct {
+	Context       []string  `json:"@context"`
+	Type          string    `json:"type"`
+	SchemaVersion string    `json:"schemaVersion"`
+	CreationDate  string    `json:"creationDate"`
+	EPCISBody     epcisBody `json:"epcisBody"`
+}
+
+type epcisBody struct {
+	EventList []epcisEvent `json:"eventList"`
+}
+
+type epcisEvent struct {
+	Type                string                `json:"type"`
+	EventTime           string                `json:"eventTime"`
+	EventTimeZoneOffset string                `json:"eventTimeZoneOffset"`
+	EPCList             []string              `json:"epcList"`
+	Action              string                `json:"action"`
+	BizStep             string                `json:"bizStep,omitempty"`
+	Disposition         string                `json:"disposition,omitempty"`
+	ReadPoint           *epcisLocation        `json:"readPoint,omitempty"`
+	BizLocation         *epcisLocation        `json:"bizLocation,omitempty"`
+	BizTransactionList  []epcisBizTransaction `json:"bizTransactionList,omitempty"`
+	SourceList          []epcisSource         `json:"sourceList,omitempty"`
+	DestinationList     []epcisDestination    `json:"destinationList,omitempty"`
+}
+
+type epcisLocation struct {
+	ID string `json:"id"`
+}
+
+type epcisBizTransaction struct {
+	Type           string `json:"type,omitempty"`
+	BizTransaction string `json:"bizTransaction"`
+}
+
+type epcisSource struct {
+	Type   string `json:"type"`
+	Source string `json:"source"`
+}
+
+type epcisDestination struct {
+	Type        string `json:"type"`
+	Destination string `json:"destination"`
+}
+
+func epcisDisposition(status string) string {
+	switch status {
+	case "Recalled":
+		return "recalled"
+	case "Returned", "Inspected":
+		return "returned"
+	case "Scrapped":
+		return "destroyed"
+	case "Split", "Merged":
+		return "inactive"
+	}
+	return "active"
+}
+
+// locationURI identifies a location by its GLN as a GS1 Digital Link URI,
+// falling back to the ledger ID for locations without one.
+func locationURI(location *Location) string {
+	if location.GLN != "" {
+		return "https://id.gs1.org/414/" + location.GLN
+	}
+	return "urn:fabric:location:" + location.ID
+}
+
+func participantURI(id string) string {
+	return "urn:fabric:participant:" + id
+}
+
+// normalizeGTIN validates a GTIN-8, -12, -13 or -14 and returns it padded to
+// 14 digits.
+func normalizeGTIN(gtin string) (string, error) {
+	switch len(gtin) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("GTIN must have 8, 12, 13 or 14 digits, got %d", len(gtin))
+	}
+	gtin = strings.Repeat("0", 14-len(gtin)) + gtin
+	if err := validateGS1Number("GTIN", gtin, 14); err != nil {
+		return "", err
+	}
+	return gtin, nil
+}
+
+func validateGS1Number(scheme, value string, length int) error {
+	if len(value) != length {
+		return fmt.Errorf("%s must have %d digits, got %d", scheme, length, len(value))
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("%s %s contains a non-digit", scheme, value)
+		}
+	}
+	if expected := gs1CheckDigit(value[:length-1]); value[length-1] != expected {
+		return fmt.Errorf("%s %s has check digit %c, expected %c", scheme, value, value[length-1], expected)
+	}
+	return nil
+}
+
+// gs1CheckDigit computes the GS1 mod-10 check digit: digits are weighted 3
+// and 1 alternately starting from the rightmost.
+func gs1CheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// sgtinURI builds urn:epc:id:sgtin:CompanyPrefix.IndicatorItemRef.Serial from
+// a 14-digit GTIN.
+func sgtinURI(gtin14, serial string, companyPrefixLength int) (string, error) {
+	if companyPrefixLength < 6 || companyPrefixLength > 12 {
+		return "", fmt.Errorf("company prefix length must be between 6 and 12, got %d", companyPrefixLength)
+	}
+	if !gs1SerialPattern.MatchString(serial) {
+		return "", fmt.Errorf("serial %q must be 1 to 20 GS1 AI encodable characters", serial)
+	}
+
+	companyPrefix := gtin14[1 : 1+companyPrefixLength]
+	itemReference := gtin14[0:1] + gtin14[1+companyPrefixLength:13]
+	return fmt.Sprintf("urn:epc:id:sgtin:%s.%s.%s", companyPrefix, itemReference, escapeEPCComponent(serial)), nil
+}
+
+func escapeEPCComponent(value string) string {
+	var b strings.Builder
+	for _, c := range value {
+		switch c {
+		case '"', '%', '&', '/', '<', '>', '?':
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+const laptopGTIN = "4006381333931"
+
+func TestAssignGS1Identifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		gtin         string
+		serial       string
+		prefixLength string
+		wantErr      string
+		wantEPC      string
+	}{
+		{"GTIN-13", laptopGTIN, "S1", "7", "", "urn:epc:id:sgtin:4006381.033393.S1"},
+		{"escaped serial", laptopGTIN, "A/1", "7", "", "urn:epc:id:sgtin:4006381.033393.A%2F1"},
+		{"bad check digit", "4006381333932", "S1", "7", "has check digit 2, expected 1", ""},
+		{"bad length", "40063813339", "S1", "7", "GTIN must have 8, 12, 13 or 14 digits", ""},
+		{"bad prefix length", laptopGTIN, "S1", "5", "company prefix length must be between 6 and 12", ""},
+		{"bad serial", laptopGTIN, "S 1", "7", "must be 1 to 20 GS1 AI encodable characters", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(org1, "AssignGS1Identifier", "p1", tt.gtin, tt.serial, tt.prefixLength)
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			if product := l.product("p1"); product.GTIN != "0"+laptopGTIN || product.EPC != tt.wantEPC {
+				t.Errorf("product GTIN %s EPC %s, want 0%s and %s", product.GTIN, product.EPC, laptopGTIN, tt.wantEPC)
+			}
+		})
+	}
+
+	l := newTestLedger(t)
+	l.run([]txStep{
+		{org2, "AssignGS1Identifier", []string{"p1", laptopGTIN, "S1", "7"}, "submitter Org2MSP is not authorized to act as owner CompanyA"},
+		{org1, "AssignGS1Identifier", []string{"p1", laptopGTIN, "S1", "7"}, ""},
+		{org1, "AssignGS1Identifier", []string{"p1", laptopGTIN, "S2", "7"}, "product p1 already has GS1 identifier"},
+		{org2, "AssignGS1Identifier", []string{"p2", laptopGTIN, "S1", "7"}, "is already assigned to product p1"},
+	})
+}
+
+func TestGetProductByEPC(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org1, "AssignGS1Identifier", "p1", laptopGTIN, "S1", "7")
+
+	var product Product
+	l.evaluate(auditor, &product, "GetProductByEPC", "urn:epc:id:sgtin:4006381.033393.S1")
+	if product.ID != "p1" {
+		t.Errorf("GetProductByEPC returned %s, want p1", product.ID)
+	}
+
+	_, err := l.ledger.Evaluate(auditor, "GetProductByEPC", "urn:epc:id:sgtin:4006381.033393.S2")
+	expectError(t, err, "no product is identified by")
+}
+
+func TestValidateGS1Identifier(t *testing.T) {
+	tests := []struct {
+		scheme  string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"gtin", "96385074", "00000096385074", ""},
+		{"gtin", laptopGTIN, "0" + laptopGTIN, ""},
+		{"sscc", "106141412345678908", "106141412345678908", ""},
+		{"sscc", "106141412345678909", "", "has check digit 9, expected 8"},
+		{"gln", "0614141000005", "0614141000005", ""},
+		{"gln", "061414100000", "", "GLN must have 13 digits"},
+		{"gln", "06141410000a5", "", "contains a non-digit"},
+		{"grai", "0614141000005", "", "scheme must be gtin, sscc or gln"},
+	}
+
+	l := newTestLedger(t)
+	for _, tt := range tests {
+		t.Run(tt.scheme+" "+tt.value, func(t *testing.T) {
+			payload, err := l.ledger.Evaluate(org3, "ValidateGS1Identifier", tt.scheme, tt.value)
+			expectError(t, err, tt.wantErr)
+			if err == nil && string(payload) != tt.want {
+				t.Errorf("ValidateGS1Identifier(%s, %s) = %s, want %s", tt.scheme, tt.value, payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProductEPCIS(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org1, "AssignGS1Identifier", "p1", laptopGTIN, "S1", "7")
+	l.submit(org1, "TransferOwnership", "p1", "CompanyB")
+
+	payload, err := l.ledger.Evaluate(org1, "GetProductEPCIS", "p1")
+	expectError(t, err, "")
+	var document epcisDocument
+	if err := json.Unmarshal([]byte(payload), &document); err != nil {
+		t.Fatalf("failed to decode EPCIS document %s: %v", payload, err)
+	}
+
+	want := []struct{ eventType, bizStep string }{
+		{"ObjectEvent", "commissioning"},
+		{"ObjectEvent", ""},
+		{"TransactionEvent", "shipping"},
+	}
+	events := document.EPCISBody.EventList
+	if len(events) != len(want) {
+		t.Fatalf("EPCIS document has %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Type != want[i].eventType || event.BizStep != want[i].bizStep || event.EPCList[0] != "urn:epc:id:sgtin:4006381.033393.S1" {
+			t.Errorf("event %d = %+v, want %s %q", i, event, want[i].eventType, want[i].bizStep)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		product   string
+		wantErr   string
+	}{
+		{"public viewer", org3, "p1", "only visible to registered participants"},
+		{"no GS1 identifier", org2, "p2", "has no GS1 identifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := l.ledger.Evaluate(tt.submitter, "GetProductEPCIS", tt.product)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
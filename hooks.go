@@ -0,0 +1,161 @@
+package smartcontract
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// SupplyChainContext carries per-transaction state resolved once by
+// beforeTransaction, so transaction functions do not each look it up again.
+type SupplyChainContext struct {
+	contractapi.TransactionContext
+	function  string
+	submitter string
+	txTime    time.Time
+	started   time.Time
+}
+
+type transactionStats struct {
+	Count         int64
+	TotalDuration time.Duration
+}
+
+// transactionMetrics holds per-peer counters for successful transactions.
+// They are only logged and never written to the ledger, so they need not be
+// deterministic.
+var transactionMetrics = struct {
+	sync.Mutex
+	stats map[string]*transactionStats
+}{stats: make(map[string]*transactionStats)}
+
+// beforeTransaction authenticates the submitter, caches the transaction
+// timestamp, logs the invocation and enforces the pause switch. Arguments are
+// only logged if LogArguments is set.
+func (s *SupplyChainContract) beforeTransaction(ctx contractapi.TransactionContextInterface) error {
+	function := transactionName(ctx)
+
+	submitter, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil || submitter == "" {
+		return fmt.Errorf("failed to authenticate submitter of %s: %v", function, err)
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	if sc, ok := ctx.(*SupplyChainContext); ok {
+		sc.function = function
+		sc.submitter = submitter
+		sc.txTime = txTime
+		sc.started = time.Now()
+	}
+
+	if s.LogArguments {
+		_, args := ctx.GetStub().GetFunctionAndParameters()
+		log.Printf("tx %s: %s invoked by %s with %s", ctx.GetStub().GetTxID(), function, submitter, summarizeArgs(function, args))
+	} else {
+		log.Printf("tx %s: %s invoked by %s", ctx.GetStub().GetTxID(), function, submitter)
+	}
+
+	return s.requireNotPaused(ctx, function)
+}
+
+// afterTransaction runs only when the transaction function succeeded. It
+// appends mutating transactions to their audit chains.
+func (s *SupplyChainContract) afterTransaction(ctx contractapi.TransactionContextInterface, result interface{}) error {
+	function := transactionName(ctx)
+
+	if !containsString(s.GetEvaluateTransactions(), function) {
+		if err := s.appendAuditEntry(ctx, function, result); err != nil {
+			return fmt.Errorf("failed to record audit entry: %v", err)
+		}
+	}
+
+	var elapsed time.Duration
+	if sc, ok := ctx.(*SupplyChainContext); ok && !sc.started.IsZero() {
+		elapsed = time.Since(sc.started)
+	}
+
+	transactionMetrics.Lock()
+	stats, ok := transactionMetrics.stats[function]
+	if !ok {
+		stats = &transactionStats{}
+		transactionMetrics.stats[function] = stats
+	}
+	stats.Count++
+	stats.TotalDuration += elapsed
+	count, total := stats.Count, stats.TotalDuration
+	transactionMetrics.Unlock()
+
+	log.Printf("tx %s: %s succeeded in %s (%d calls, avg %s)", ctx.GetStub().GetTxID(), function, elapsed, count, total/time.Duration(count))
+	return nil
+}
+
+func (s *SupplyChainContract) unknownTransaction(ctx contractapi.TransactionContextInterface) error {
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	return fmt.Errorf("unknown transaction %q; valid transactions are: %s", function, strings.Join(s.transactionNames(), ", "))
+}
+
+// transactionNames lists the exported methods contractapi exposes as
+// transactions, excluding those inherited from contractapi.Contract.
+func (s *SupplyChainContract) transactionNames() []string {
+	inherited := make(map[string]bool)
+	contractType := reflect.TypeOf(&contractapi.Contract{})
+	for i := 0; i < contractType.NumMethod(); i++ {
+		inherited[contractType.Method(i).Name] = true
+	}
+	inherited["GetEvaluateTransactions"] = true
+
+	var names []string
+	methods := reflect.TypeOf(s)
+	for i := 0; i < methods.NumMethod(); i++ {
+		if name := methods.Method(i).Name; !inherited[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summarizeArgs renders an invocation's arguments for the log, truncating long
+// ones. An authenticity code is replaced by its SHA-256 hash, which also
+// identifies its audit chain, so scanned codes never reach peer logs.
+func summarizeArgs(function string, args []string) string {
+	if len(args) == 0 {
+		return "no arguments"
+	}
+
+	if chainType, chainID := auditChainFor(function, args); chainType == authTokenObjectType {
+		args = append([]string{chainID}, args[1:]...)
+	}
+
+	summary := make([]string, len(args))
+	for i, arg := range args {
+		if len(arg) > 64 {
+			arg = arg[:64] + "..."
+		}
+		summary[i] = fmt.Sprintf("%q", arg)
+	}
+	return "arguments [" + strings.Join(summary, " ") + "]"
+}
+
+// transactionName returns the invoked function without its contract
+// namespace prefix.
+func transactionName(ctx contractapi.TransactionContextInterface) string {
+	if sc, ok := ctx.(*SupplyChainContext); ok && sc.function != "" {
+		return sc.function
+	}
+
+	function, _ := ctx.GetStub().GetFunctionAndParameters()
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		function = function[i+1:]
+	}
+	return function
+}
@@ -0,0 +1,116 @@
+package smartcontract
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func TestUnknownTransaction(t *testing.T) {
+	l := newTestLedger(t)
+	_, err := l.ledger.Submit(org1, "TransferProduct", "p1", "CompanyB")
+	expectError(t, err, `unknown transaction "TransferProduct"; valid transactions are:`)
+	expectError(t, err, "TransferOwnership")
+}
+
+func TestTransactionNames(t *testing.T) {
+	names := NewContract().transactionNames()
+
+	for _, name := range []string{"CreateProduct", "QueryProduct", "SetPaused"} {
+		if !containsString(names, name) {
+			t.Errorf("transaction names %v do not include %s", names, name)
+		}
+	}
+	for _, name := range []string{"GetName", "GetEvaluateTransactions", "GetUnknownTransaction"} {
+		if containsString(names, name) {
+			t.Errorf("transaction names include inherited %s", name)
+		}
+	}
+}
+
+// newLoggingLedger returns an initialized ledger on its own chaincode, whose
+// contract logs arguments if logArguments is set.
+func newLoggingLedger(t *testing.T, logArguments bool) *testLedger {
+	t.Helper()
+	contract := NewContract()
+	contract.LogArguments = logArguments
+	chaincode, err := contractapi.NewChaincode(contract)
+	if err != nil {
+		t.Fatalf("failed to create chaincode: %v", err)
+	}
+	l := &testLedger{t: t, ledger: mockledger.New(chaincode)}
+	l.submit(admin, "InitLedger")
+	return l
+}
+
+// captureLog returns the standard logger's output until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	return &buf
+}
+
+func TestTransactionLogging(t *testing.T) {
+	tests := []struct {
+		name         string
+		logArguments bool
+		want         string
+	}{
+		{"default", false, "TransferOwnership invoked by Org1MSP\n"},
+		{"arguments enabled", true, `TransferOwnership invoked by Org1MSP with arguments ["p1" "CompanyB"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLoggingLedger(t, tt.logArguments)
+			buf := captureLog(t)
+			l.submit(org1, "TransferOwnership", "p1", "CompanyB")
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("log %q does not contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeArgs(t *testing.T) {
+	tests := []struct {
+		function string
+		args     []string
+		want     string
+	}{
+		{"InitLedger", nil, "no arguments"},
+		{"TransferOwnership", []string{"p1", "CompanyB"}, `arguments ["p1" "CompanyB"]`},
+		{"CreateProduct", []string{strings.Repeat("x", 70)}, `arguments ["` + strings.Repeat("x", 64) + `..."]`},
+		{"VerifyAuthenticity", []string{"0123456789abcdef"}, `arguments ["` + sha256Hex([]byte("0123456789abcdef")) + `"]`},
+	}
+
+	for _, tt := range tests {
+		if got := summarizeArgs(tt.function, tt.args); got != tt.want {
+			t.Errorf("summarizeArgs(%s, %q) = %s, want %s", tt.function, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticityCodeIsNotLogged(t *testing.T) {
+	l := newLoggingLedger(t, true)
+	if _, err := l.ledger.SubmitTransient(org1, authSecret, "GenerateAuthenticityToken", "p1"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := captureLog(t)
+	code := authCode("p1")
+	l.verify(code)
+	if strings.Contains(buf.String(), code) {
+		t.Errorf("log %q contains authenticity code %s", buf.String(), code)
+	}
+	if !strings.Contains(buf.String(), "VerifyAuthenticity invoked by Org3MSP with arguments") {
+		t.Errorf("log %q does not record the scan", buf.String())
+	}
+}
@@ -0,0 +1,84 @@
+// Package backend runs supply chain contract transactions either on a Fabric
+// network through the Fabric Gateway or in process against an in-memory
+// ledger, behind one interface for the gateway and CLI commands.
+package backend
+
+import (
+	"fmt"
+
+	"github.com/Joeychen80627/smartcontract"
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Backend submits and evaluates contract transactions. Arguments are passed
+// as the contract expects them: strings as-is, numbers in decimal, and
+// slices, maps and structs as JSON.
+type Backend interface {
+	Submit(function string, args ...string) ([]byte, error)
+	Evaluate(function string, args ...string) ([]byte, error)
+	Close() error
+}
+
+// ContractError is a transaction rejected by the contract, or by the
+// network on the contract's behalf, as opposed to a failure to reach it.
+type ContractError struct {
+	Message string
+}
+
+func (e *ContractError) Error() string {
+	return e.Message
+}
+
+// InProcess runs the contract in this process against an in-memory ledger.
+// Nothing is persisted.
+type InProcess struct {
+	ledger   *mockledger.Ledger
+	identity mockledger.Identity
+}
+
+// NewInProcess starts the contract on an empty in-memory ledger, submitting
+// as mspID with the given role certificate attribute (empty for none). If
+// seed is set, InitLedger is run first by an admin of mspID.
+func NewInProcess(mspID, role string, seed bool) (*InProcess, error) {
+	chaincode, err := contractapi.NewChaincode(smartcontract.NewContract())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chaincode: %v", err)
+	}
+
+	b := &InProcess{
+		ledger:   mockledger.New(chaincode),
+		identity: mockledger.Identity{MSPID: mspID, Role: role},
+	}
+	if seed {
+		if _, err := b.As(mspID, "admin").Submit("InitLedger"); err != nil {
+			return nil, fmt.Errorf("failed to seed ledger: %v", err)
+		}
+	}
+	return b, nil
+}
+
+// As returns a backend sharing the same ledger that submits as a different
+// identity.
+func (b *InProcess) As(mspID, role string) Backend {
+	return &InProcess{ledger: b.ledger, identity: mockledger.Identity{MSPID: mspID, Role: role}}
+}
+
+func (b *InProcess) Submit(function string, args ...string) ([]byte, error) {
+	return contractResult(b.ledger.Submit(b.identity, function, args...))
+}
+
+func (b *InProcess) Evaluate(function string, args ...string) ([]byte, error) {
+	return contractResult(b.ledger.Evaluate(b.identity, function, args...))
+}
+
+func (b *InProcess) Close() error {
+	return nil
+}
+
+func contractResult(payload []byte, err error) ([]byte, error) {
+	if ledgerErr, ok := err.(*mockledger.Error); ok {
+		return nil, &ContractError{Message: ledgerErr.Message}
+	}
+	return payload, err
+}
@@ -0,0 +1,152 @@
+package backend
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// FabricConfig locates a gateway peer and the client identity used to
+// connect to it.
+type FabricConfig struct {
+	PeerEndpoint string
+	// GatewayPeer overrides the TLS server name, for peers reached through
+	// an address that does not match their certificate.
+	GatewayPeer string
+	TLSCertPath string
+	MSPID       string
+	CertPath    string
+	KeyPath     string
+	Channel     string
+	Chaincode   string
+}
+
+// Fabric runs transactions on a Fabric network through a gateway peer.
+type Fabric struct {
+	connection *grpc.ClientConn
+	gateway    *client.Gateway
+	contract   *client.Contract
+}
+
+var chaincodeResponsePrefix = regexp.MustCompile(`^chaincode response \d+, `)
+
+func DialFabric(config FabricConfig) (*Fabric, error) {
+	tlsCertPEM, err := os.ReadFile(config.TLSCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate: %v", err)
+	}
+	tlsCert, err := identity.CertificateFromPEM(tlsCertPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TLS certificate: %v", err)
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(tlsCert)
+
+	connection, err := grpc.NewClient(config.PeerEndpoint, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(certPool, config.GatewayPeer)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection to %s: %v", config.PeerEndpoint, err)
+	}
+
+	id, sign, err := loadIdentity(config)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	gw, err := client.Connect(id,
+		client.WithSign(sign),
+		client.WithClientConnection(connection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(time.Minute),
+	)
+	if err != nil {
+		connection.Close()
+		return nil, fmt.Errorf("failed to connect to gateway: %v", err)
+	}
+
+	return &Fabric{
+		connection: connection,
+		gateway:    gw,
+		contract:   gw.GetNetwork(config.Channel).GetContract(config.Chaincode),
+	}, nil
+}
+
+func loadIdentity(config FabricConfig) (*identity.X509Identity, identity.Sign, error) {
+	certPEM, err := os.ReadFile(config.CertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read client certificate: %v", err)
+	}
+	cert, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse client certificate: %v", err)
+	}
+	id, err := identity.NewX509Identity(config.MSPID, cert)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := os.ReadFile(config.KeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read private key: %v", err)
+	}
+	key, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	sign, err := identity.NewPrivateKeySign(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return id, sign, nil
+}
+
+func (b *Fabric) Submit(function string, args ...string) ([]byte, error) {
+	result, err := b.contract.SubmitTransaction(function, args...)
+	return result, fabricError(err)
+}
+
+func (b *Fabric) Evaluate(function string, args ...string) ([]byte, error) {
+	result, err := b.contract.EvaluateTransaction(function, args...)
+	return result, fabricError(err)
+}
+
+func (b *Fabric) Close() error {
+	b.gateway.Close()
+	return b.connection.Close()
+}
+
+// fabricError turns errors that carry the contract's own message, and
+// transactions that endorsed but failed validation, into ContractErrors.
+// The gateway reports the contract's message in an error detail from each
+// endorsing peer, prefixed with the chaincode response status. Anything else
+// is a problem reaching the network and is returned as is.
+func fabricError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		return &ContractError{Message: commitErr.Error()}
+	}
+
+	for _, detail := range status.Convert(err).Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok && chaincodeResponsePrefix.MatchString(detail.GetMessage()) {
+			return &ContractError{Message: chaincodeResponsePrefix.ReplaceAllString(detail.GetMessage(), "")}
+		}
+	}
+	return err
+}
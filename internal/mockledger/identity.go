@@ -0,0 +1,73 @@
+package mockledger
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/protobuf/proto"
+)
+
+// attributesOID is the certificate extension in which Fabric CA records
+// identity attributes, and from which the chaincode cid package reads them.
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// creator returns the serialized identity presented to the chaincode for id,
+// issuing it a certificate the first time it is seen.
+func (l *Ledger) creator(id Identity) ([]byte, error) {
+	if creator, ok := l.creators[id]; ok {
+		return creator, nil
+	}
+	if id.MSPID == "" {
+		return nil, fmt.Errorf("identity has no MSP ID")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key for %s: %v", id.MSPID, err)
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(len(l.creators) + 1)),
+		Subject: pkix.Name{
+			CommonName:   fmt.Sprintf("%s %s", id.MSPID, id.Role),
+			Organization: []string{id.MSPID},
+		},
+		NotBefore: notBefore,
+		NotAfter:  notBefore.AddDate(1, 0, 0),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}
+	if id.Role != "" {
+		attributes, err := json.Marshal(map[string]map[string]string{"attrs": {"role": id.Role}})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attributesOID, Value: attributes}}
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate for %s: %v", id.MSPID, err)
+	}
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   id.MSPID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize identity for %s: %v", id.MSPID, err)
+	}
+
+	l.creators[id] = creator
+	return creator, nil
+}
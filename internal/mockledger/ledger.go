@@ -0,0 +1,174 @@
+// Package mockledger runs chaincode against an in-memory world state, for
+// local testing and dry runs without a Fabric network.
+//
+// Transactions follow Fabric's rules where the contract depends on them:
+// reads see only committed state, writes are buffered and committed only when
+// the transaction succeeds, evaluated transactions never commit, and range
+// queries over simple keys skip composite keys, and paginated queries are
+// refused in transactions that write, and vice versa. Each transaction is
+// submitted by an Identity, presented to the contract as a self-signed X.509
+// certificate so that client identity checks behave as on a peer.
+// Transactions run one at a time, so there are no MVCC conflicts.
+package mockledger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Identity is the submitter of a transaction.
+type Identity struct {
+	MSPID string
+	// Role is the value of the "role" certificate attribute, or empty for
+	// none.
+	Role string
+}
+
+// Error is a transaction failure reported by the chaincode.
+type Error struct {
+	Status  int32
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Ledger struct {
+	mu                   sync.Mutex
+	chaincode            shim.Chaincode
+	channel              string
+	state                map[string][]byte
+	history              map[string][]*queryresult.KeyModification
+	validationParameters map[string][]byte
+	creators             map[Identity][]byte
+	lastTxTime           time.Time
+	txCount              int
+}
+
+// New returns an empty ledger running chaincode.
+func New(chaincode shim.Chaincode) *Ledger {
+	return &Ledger{
+		chaincode:            chaincode,
+		channel:              "mockchannel",
+		state:                make(map[string][]byte),
+		history:              make(map[string][]*queryresult.KeyModification),
+		validationParameters: make(map[string][]byte),
+		creators:             make(map[Identity][]byte),
+	}
+}
+
+// PutState writes a value directly into committed state, as if written by
+// an earlier transaction, for seeding records the chaincode would no longer
+// write itself.
+func (l *Ledger) PutState(key string, value []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state[key] = value
+}
+
+// Submit runs a transaction and commits its writes if it succeeds.
+func (l *Ledger) Submit(id Identity, function string, args ...string) ([]byte, error) {
+	return l.invoke(id, true, nil, function, args)
+}
+
+// SubmitTransient is Submit with transient data, which the chaincode reads
+// but which is not recorded on the ledger.
+func (l *Ledger) SubmitTransient(id Identity, transient map[string][]byte, function string, args ...string) ([]byte, error) {
+	return l.invoke(id, true, transient, function, args)
+}
+
+// Evaluate runs a transaction and discards its writes.
+func (l *Ledger) Evaluate(id Identity, function string, args ...string) ([]byte, error) {
+	return l.invoke(id, false, nil, function, args)
+}
+
+func (l *Ledger) invoke(id Identity, commit bool, transient map[string][]byte, function string, args []string) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	creator, err := l.creator(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep transaction times strictly increasing so that history is ordered
+	// even when transactions land within the clock's resolution.
+	txTime := time.Now().UTC()
+	if !txTime.After(l.lastTxTime) {
+		txTime = l.lastTxTime.Add(time.Nanosecond)
+	}
+	l.lastTxTime = txTime
+	l.txCount++
+
+	stub := &txStub{
+		ledger:                 l,
+		txID:                   fmt.Sprintf("%064x", l.txCount),
+		txTime:                 txTime,
+		creator:                creator,
+		args:                   append([]string{function}, args...),
+		transient:              transient,
+		writes:                 make(map[string][]byte),
+		deletes:                make(map[string]bool),
+		validationParameterSet: make(map[string][]byte),
+	}
+
+	response := l.chaincode.Invoke(stub)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		return nil, &Error{Status: response.Status, Message: response.Message}
+	}
+	if commit {
+		l.commit(stub)
+	}
+	return response.Payload, nil
+}
+
+func (l *Ledger) commit(stub *txStub) {
+	timestamp := timestamppb.New(stub.txTime)
+	for key, value := range stub.writes {
+		l.state[key] = value
+		l.history[key] = append(l.history[key], &queryresult.KeyModification{TxId: stub.txID, Value: value, Timestamp: timestamp})
+	}
+	for key := range stub.deletes {
+		delete(l.state, key)
+		delete(l.validationParameters, key)
+		l.history[key] = append(l.history[key], &queryresult.KeyModification{TxId: stub.txID, Timestamp: timestamp, IsDelete: true})
+	}
+	for key, ep := range stub.validationParameterSet {
+		l.validationParameters[key] = ep
+	}
+}
+
+// keysInRange returns the committed keys k with start <= k < end in order;
+// an empty end leaves the range open.
+func (l *Ledger) keysInRange(start, end string) []string {
+	var keys []string
+	for key := range l.state {
+		if key >= start && (end == "" || key < end) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// historyFor returns the key's modifications newest first, as Fabric does.
+func (l *Ledger) historyFor(key string) []*queryresult.KeyModification {
+	modifications := l.history[key]
+	reversed := make([]*queryresult.KeyModification, len(modifications))
+	for i, modification := range modifications {
+		reversed[len(modifications)-1-i] = modification
+	}
+	return reversed
+}
+
+func isCompositeKey(key string) bool {
+	return strings.HasPrefix(key, compositeKeyNamespace)
+}
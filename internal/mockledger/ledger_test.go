@@ -0,0 +1,226 @@
+package mockledger
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	pb "github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// kvChaincode exposes stub operations directly as transactions.
+type kvChaincode struct{}
+
+func (kvChaincode) Init(stub shim.ChaincodeStubInterface) *pb.Response {
+	return shim.Success(nil)
+}
+
+func (kvChaincode) Invoke(stub shim.ChaincodeStubInterface) *pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	switch function {
+	case "put":
+		if err := stub.PutState(args[0], []byte(args[1])); err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "putThenFail":
+		stub.PutState(args[0], []byte(args[1]))
+		return shim.Error("failed after writing")
+	case "putThenGet":
+		stub.PutState(args[0], []byte(args[1]))
+		value, _ := stub.GetState(args[0])
+		return shim.Success(value)
+	case "putComposite":
+		key, err := stub.CreateCompositeKey(args[0], args[1:2])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		stub.PutState(key, []byte(args[2]))
+		return shim.Success(nil)
+	case "get":
+		value, _ := stub.GetState(args[0])
+		return shim.Success(value)
+	case "range":
+		pageSize, _ := strconv.Atoi(args[0])
+		iterator, metadata, err := stub.GetStateByRangeWithPagination("", "", int32(pageSize), args[1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var keys []string
+		for iterator.HasNext() {
+			kv, _ := iterator.Next()
+			keys = append(keys, kv.Key)
+		}
+		return shim.Success([]byte(strings.Join(keys, ",") + "|" + metadata.Bookmark))
+	case "putThenRange":
+		stub.PutState(args[0], []byte(args[1]))
+		if _, _, err := stub.GetStateByRangeWithPagination("", "", 1, ""); err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "rangeThenPut":
+		if _, _, err := stub.GetStateByRangeWithPagination("", "", 1, ""); err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := stub.PutState(args[0], []byte(args[1])); err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "history":
+		iterator, _ := stub.GetHistoryForKey(args[0])
+		var values []string
+		for iterator.HasNext() {
+			modification, _ := iterator.Next()
+			values = append(values, string(modification.Value))
+		}
+		return shim.Success([]byte(strings.Join(values, ",")))
+	}
+	return shim.Error("unknown function " + function)
+}
+
+var org1 = Identity{MSPID: "Org1MSP"}
+
+func TestWritesCommitOnlyWhenSubmittedTransactionSucceeds(t *testing.T) {
+	tests := []struct {
+		name     string
+		submit   bool
+		function string
+		want     string
+	}{
+		{"submitted success commits", true, "put", "new"},
+		{"submitted failure rolls back", true, "putThenFail", "old"},
+		{"evaluated success discards", false, "put", "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(kvChaincode{})
+			if _, err := l.Submit(org1, "put", "k", "old"); err != nil {
+				t.Fatal(err)
+			}
+
+			run := l.Evaluate
+			if tt.submit {
+				run = l.Submit
+			}
+			run(org1, tt.function, "k", "new")
+
+			got, err := l.Evaluate(org1, "get", "k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("k = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadsDoNotSeeOwnWrites(t *testing.T) {
+	l := New(kvChaincode{})
+	got, err := l.Submit(org1, "putThenGet", "k", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("read in writing transaction = %q, want nothing", got)
+	}
+}
+
+func TestFailureIsReportedAsError(t *testing.T) {
+	l := New(kvChaincode{})
+	_, err := l.Submit(org1, "putThenFail", "k", "v")
+	ledgerErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if ledgerErr.Message != "failed after writing" || ledgerErr.Status < shim.ERRORTHRESHOLD {
+		t.Errorf("err = %+v", ledgerErr)
+	}
+}
+
+func TestRangeQueries(t *testing.T) {
+	l := New(kvChaincode{})
+	for _, key := range []string{"c", "a", "b"} {
+		if _, err := l.Submit(org1, "put", key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := l.Submit(org1, "putComposite", "Type", "a", "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		pageSize string
+		bookmark string
+		want     string
+	}{
+		{"whole range skips composite keys", "0", "", "a,b,c|"},
+		{"first page", "2", "", "a,b|c"},
+		{"last page", "2", "c", "c|"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Evaluate(org1, "range", tt.pageSize, tt.bookmark)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("range = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginatedQueriesAreReadOnly(t *testing.T) {
+	tests := []struct {
+		function string
+		wantErr  string
+	}{
+		{"putThenRange", "paginated queries are supported only in a read-only transaction"},
+		{"rangeThenPut", "transaction has already performed a paginated query, writes are not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			l := New(kvChaincode{})
+			_, err := l.Submit(org1, tt.function, "k", "v")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPutStateSeedsCommittedState(t *testing.T) {
+	l := New(kvChaincode{})
+	l.PutState("k", []byte("seeded"))
+
+	got, err := l.Evaluate(org1, "get", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "seeded" {
+		t.Errorf("k = %q, want seeded", got)
+	}
+}
+
+func TestHistoryIsNewestFirst(t *testing.T) {
+	l := New(kvChaincode{})
+	for _, value := range []string{"1", "2", "3"} {
+		if _, err := l.Submit(org1, "put", "k", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := l.Evaluate(org1, "history", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "3,2,1" {
+		t.Errorf("history = %q, want %q", got, "3,2,1")
+	}
+}
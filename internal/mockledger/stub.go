@@ -0,0 +1,339 @@
+package mockledger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	compositeKeyNamespace = "\x00"
+	minUnicodeRuneValue   = 0
+	maxUnicodeRuneValue   = utf8.MaxRune
+	emptyKeySubstitute    = "\x01"
+)
+
+var errUnsupported = errors.New("not supported by the in-memory ledger")
+
+// txStub is the stub for a single transaction. Stub methods this ledger
+// does not model fall through to the nil embedded interface and panic.
+type txStub struct {
+	shim.ChaincodeStubInterface
+
+	ledger                 *Ledger
+	txID                   string
+	txTime                 time.Time
+	creator                []byte
+	args                   []string
+	transient              map[string][]byte
+	writes                 map[string][]byte
+	deletes                map[string]bool
+	validationParameterSet map[string][]byte
+	// paginated records that the transaction ran a paginated query, after
+	// which Fabric refuses writes.
+	paginated bool
+}
+
+func (s *txStub) GetArgs() [][]byte {
+	args := make([][]byte, len(s.args))
+	for i, arg := range s.args {
+		args[i] = []byte(arg)
+	}
+	return args
+}
+
+func (s *txStub) GetStringArgs() []string {
+	return append([]string(nil), s.args...)
+}
+
+func (s *txStub) GetFunctionAndParameters() (string, []string) {
+	return s.args[0], append([]string(nil), s.args[1:]...)
+}
+
+func (s *txStub) GetArgsSlice() ([]byte, error) {
+	return []byte(strings.Join(s.args, "")), nil
+}
+
+func (s *txStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *txStub) GetChannelID() string {
+	return s.ledger.channel
+}
+
+func (s *txStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) *pb.Response {
+	return &pb.Response{Status: shim.ERROR, Message: "chaincode-to-chaincode calls are " + errUnsupported.Error()}
+}
+
+func (s *txStub) GetState(key string) ([]byte, error) {
+	return s.ledger.state[key], nil
+}
+
+func (s *txStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	s.writes[key] = value
+	delete(s.deletes, key)
+	return nil
+}
+
+func (s *txStub) DelState(key string) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	delete(s.writes, key)
+	s.deletes[key] = true
+	return nil
+}
+
+func (s *txStub) SetStateValidationParameter(key string, ep []byte) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	s.validationParameterSet[key] = ep
+	return nil
+}
+
+func (s *txStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.ledger.validationParameters[key], nil
+}
+
+func (s *txStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.rangeQuery(startKey, endKey, 0, "")
+	return iterator, err
+}
+
+func (s *txStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := s.checkPaginatedQuery(); err != nil {
+		return nil, nil, err
+	}
+	return s.rangeQuery(startKey, endKey, pageSize, bookmark)
+}
+
+func (s *txStub) rangeQuery(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if isCompositeKey(startKey) || isCompositeKey(endKey) {
+		return nil, nil, fmt.Errorf("range query keys must be simple keys")
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	iterator, metadata := s.page(startKey, endKey, pageSize, bookmark)
+	return iterator, metadata, nil
+}
+
+func (s *txStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.partialCompositeKeyQuery(objectType, keys, 0, "")
+	return iterator, err
+}
+
+func (s *txStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := s.checkPaginatedQuery(); err != nil {
+		return nil, nil, err
+	}
+	return s.partialCompositeKeyQuery(objectType, keys, pageSize, bookmark)
+}
+
+func (s *txStub) partialCompositeKeyQuery(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	iterator, metadata := s.page(prefix, prefix+string(rune(maxUnicodeRuneValue)), pageSize, bookmark)
+	return iterator, metadata, nil
+}
+
+// checkPaginatedQuery and checkWrite enforce Fabric's rule that paginated
+// queries are only allowed in read-only transactions, whichever comes first.
+func (s *txStub) checkPaginatedQuery() error {
+	if len(s.writes) > 0 || len(s.deletes) > 0 || len(s.validationParameterSet) > 0 {
+		return fmt.Errorf("txid [%s]: paginated queries are supported only in a read-only transaction", s.txID)
+	}
+	s.paginated = true
+	return nil
+}
+
+func (s *txStub) checkWrite() error {
+	if s.paginated {
+		return fmt.Errorf("txid [%s]: transaction has already performed a paginated query, writes are not allowed", s.txID)
+	}
+	return nil
+}
+
+// page returns up to pageSize committed entries in [start, end), resuming
+// at bookmark, which is the first key of the next page. A pageSize of zero
+// returns the whole range.
+func (s *txStub) page(start, end string, pageSize int32, bookmark string) (*stateIterator, *pb.QueryResponseMetadata) {
+	if bookmark > start {
+		start = bookmark
+	}
+	keys := s.ledger.keysInRange(start, end)
+
+	metadata := &pb.QueryResponseMetadata{}
+	if pageSize > 0 && len(keys) > int(pageSize) {
+		metadata.Bookmark = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+	metadata.FetchedRecordsCount = int32(len(keys))
+
+	results := make([]*queryresult.KV, len(keys))
+	for i, key := range keys {
+		results[i] = &queryresult.KV{Namespace: "", Key: key, Value: s.ledger.state[key]}
+	}
+	return &stateIterator{results: results}, metadata
+}
+
+func (s *txStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	key := compositeKeyNamespace + objectType + string(rune(minUnicodeRuneValue))
+	for _, attribute := range attributes {
+		if err := validateCompositeKeyAttribute(attribute); err != nil {
+			return "", err
+		}
+		key += attribute + string(rune(minUnicodeRuneValue))
+	}
+	return key, nil
+}
+
+func (s *txStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	var components []string
+	start := 1
+	for i := 1; i < len(compositeKey); i++ {
+		if compositeKey[i] == minUnicodeRuneValue {
+			components = append(components, compositeKey[start:i])
+			start = i + 1
+		}
+	}
+	if len(components) == 0 {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	return components[0], components[1:], nil
+}
+
+func validateCompositeKeyAttribute(value string) error {
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("composite key attribute %q is not valid UTF-8", value)
+	}
+	for _, r := range value {
+		if r == minUnicodeRuneValue || r == maxUnicodeRuneValue {
+			return fmt.Errorf("composite key attribute %q contains U+%04X, which is reserved", value, r)
+		}
+	}
+	return nil
+}
+
+func (s *txStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("rich queries are %v", errUnsupported)
+}
+
+func (s *txStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("rich queries are %v", errUnsupported)
+}
+
+func (s *txStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{results: s.ledger.historyFor(key)}, nil
+}
+
+func (s *txStub) GetPrivateData(collection, key string) ([]byte, error) {
+	return nil, fmt.Errorf("private data is %v", errUnsupported)
+}
+
+func (s *txStub) PutPrivateData(collection, key string, value []byte) error {
+	return fmt.Errorf("private data is %v", errUnsupported)
+}
+
+func (s *txStub) DelPrivateData(collection, key string) error {
+	return fmt.Errorf("private data is %v", errUnsupported)
+}
+
+func (s *txStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *txStub) GetTransient() (map[string][]byte, error) {
+	transient := make(map[string][]byte, len(s.transient))
+	for key, value := range s.transient {
+		transient[key] = value
+	}
+	return transient, nil
+}
+
+func (s *txStub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+func (s *txStub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+func (s *txStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return nil, fmt.Errorf("signed proposals are %v", errUnsupported)
+}
+
+func (s *txStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.txTime), nil
+}
+
+// SetEvent validates the event but does not deliver it; the in-memory
+// ledger has no event listeners.
+func (s *txStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name must not be an empty string")
+	}
+	return nil
+}
+
+type stateIterator struct {
+	results []*queryresult.KV
+}
+
+func (it *stateIterator) HasNext() bool {
+	return len(it.results) > 0
+}
+
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if len(it.results) == 0 {
+		return nil, errors.New("no more results")
+	}
+	result := it.results[0]
+	it.results = it.results[1:]
+	return result, nil
+}
+
+func (it *stateIterator) Close() error {
+	return nil
+}
+
+type historyIterator struct {
+	results []*queryresult.KeyModification
+}
+
+func (it *historyIterator) HasNext() bool {
+	return len(it.results) > 0
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if len(it.results) == 0 {
+		return nil, errors.New("no more results")
+	}
+	result := it.results[0]
+	it.results = it.results[1:]
+	return result, nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
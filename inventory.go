@@ -0,0 +1,263 @@
+package smartcontract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func (s *SupplyChainContract) CreateStockProduct(ctx contractapi.TransactionContextInterface, id, name, owner, description, category string, quantity int64, unit string) error {
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be positive, got %d", quantity)
+	}
+	if unit == "" {
+		return fmt.Errorf("unit of measure is required for stock products")
+	}
+	if err := s.requireActiveParticipant(ctx, owner); err != nil {
+		return err
+	}
+	if err := s.validateCategory(ctx, category); err != nil {
+		return err
+	}
+	if err := s.validateAttributes(ctx, category, nil); err != nil {
+		return err
+	}
+
+	exists, err := s.ProductExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("product with ID %s already exists", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	newProduct := Product{
+		ID:          id,
+		Name:        name,
+		Status:      "Manufactured",
+		Owner:       owner,
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
+		Description: description,
+		Category:    category,
+		Quantity:    quantity,
+		Unit:        unit,
+	}
+
+	err = s.putProduct(ctx, &newProduct)
+	if err != nil {
+		return fmt.Errorf("failed to put product into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// SplitProduct moves the given quantities off a stock product into new child
+// products at the parent's location. Any remainder stays on the parent; a
+// fully divided parent is marked Split and no longer held at any location.
+// It is submitted by the parent's owner.
+func (s *SupplyChainContract) SplitProduct(ctx contractapi.TransactionContextInterface, id string, childIDs []string, quantities []int64) ([]*Product, error) {
+	if len(childIDs) == 0 {
+		return nil, fmt.Errorf("at least one child product is required")
+	}
+	if len(childIDs) != len(quantities) {
+		return nil, fmt.Errorf("got %d child IDs but %d quantities", len(childIDs), len(quantities))
+	}
+
+	parent, err := s.readProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireSubmitterActsFor(ctx, parent.Owner, "owner"); err != nil {
+		return nil, err
+	}
+
+	var total int64
+	seen := make(map[string]bool)
+	for i, childID := range childIDs {
+		if quantities[i] <= 0 {
+			return nil, fmt.Errorf("quantity for child %s must be positive, got %d", childID, quantities[i])
+		}
+		if childID == id || seen[childID] {
+			return nil, fmt.Errorf("duplicate product ID %s in split", childID)
+		}
+		seen[childID] = true
+
+		exists, err := s.ProductExists(ctx, childID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("product with ID %s already exists", childID)
+		}
+		total += quantities[i]
+	}
+	if total > parent.Quantity {
+		return nil, fmt.Errorf("cannot split %d %s from product %s holding %d", total, parent.Unit, id, parent.Quantity)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	footprints := splitFootprint(parent.Footprint, append(append([]int64{}, quantities...), parent.Quantity-total))
+
+	var children []*Product
+	for i, childID := range childIDs {
+		child := &Product{
+			ID:          childID,
+			Name:        parent.Name,
+			Status:      parent.Status,
+			Owner:       parent.Owner,
+			CreatedAt:   txTime,
+			UpdatedAt:   txTime,
+			Description: parent.Description,
+			Category:    parent.Category,
+			Quantity:    quantities[i],
+			Unit:        parent.Unit,
+			Location:    parent.Location,
+			Parents:     []string{parent.ID},
+			Attributes:  parent.Attributes,
+			Footprint:   footprints[i],
+		}
+		if err := s.putProduct(ctx, child); err != nil {
+			return nil, fmt.Errorf("failed to put product into ledger: %v", err)
+		}
+		if child.Location != "" {
+			if err := setLocationIndex(ctx, child.Location, child.ID, true); err != nil {
+				return nil, err
+			}
+		}
+		children = append(children, child)
+	}
+
+	parent.Footprint = footprints[len(childIDs)]
+	parent.Quantity -= total
+	parent.Children = append(parent.Children, childIDs...)
+	if parent.Quantity == 0 {
+		parent.Status = "Split"
+		if parent.Location != "" {
+			if err := setLocationIndex(ctx, parent.Location, parent.ID, false); err != nil {
+				return nil, err
+			}
+			parent.Location = ""
+		}
+	}
+	parent.UpdatedAt = txTime
+
+	if err := s.putProduct(ctx, parent); err != nil {
+		return nil, fmt.Errorf("failed to update product: %v", err)
+	}
+
+	return children, nil
+}
+
+// MergeProducts combines compatible stock products (same name, category, unit
+// and owner) into a new product holding their total quantity. It is submitted
+// by their owner. The sources are no longer held at any location; the merged
+// product is at their location if they were all at the same one.
+func (s *SupplyChainContract) MergeProducts(ctx contractapi.TransactionContextInterface, ids []string, newID string) (*Product, error) {
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("at least two products are required to merge")
+	}
+
+	exists, err := s.ProductExists(ctx, newID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("product with ID %s already exists", newID)
+	}
+
+	var sources []*Product
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate product ID %s in merge", id)
+		}
+		seen[id] = true
+
+		product, err := s.readProduct(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if product.Quantity <= 0 {
+			return nil, fmt.Errorf("product %s has no remaining quantity", id)
+		}
+		if len(sources) > 0 {
+			first := sources[0]
+			if product.Name != first.Name || product.Category != first.Category || product.Unit != first.Unit || product.Owner != first.Owner {
+				return nil, fmt.Errorf("product %s is not compatible with product %s", id, first.ID)
+			}
+		}
+		sources = append(sources, product)
+	}
+	if err := s.requireSubmitterActsFor(ctx, sources[0].Owner, "owner"); err != nil {
+		return nil, err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	first := sources[0]
+	merged := &Product{
+		ID:          newID,
+		Name:        first.Name,
+		Status:      first.Status,
+		Owner:       first.Owner,
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
+		Description: first.Description,
+		Category:    first.Category,
+		Unit:        first.Unit,
+		Parents:     ids,
+		Attributes:  first.Attributes,
+	}
+
+	merged.Location = first.Location
+	for _, source := range sources {
+		if source.Location != merged.Location {
+			merged.Location = ""
+		}
+	}
+
+	for _, source := range sources {
+		if source.Location != "" {
+			if err := setLocationIndex(ctx, source.Location, source.ID, false); err != nil {
+				return nil, err
+			}
+			source.Location = ""
+		}
+
+		merged.Quantity += source.Quantity
+		merged.Footprint = addFootprint(merged.Footprint, source.Footprint)
+		source.Footprint = nil
+
+		source.Quantity = 0
+		source.Status = "Merged"
+		source.Children = append(source.Children, newID)
+		source.UpdatedAt = txTime
+		if err := s.putProduct(ctx, source); err != nil {
+			return nil, fmt.Errorf("failed to update product: %v", err)
+		}
+	}
+
+	if err := s.putProduct(ctx, merged); err != nil {
+		return nil, fmt.Errorf("failed to put product into ledger: %v", err)
+	}
+	if merged.Location != "" {
+		if err := setLocationIndex(ctx, merged.Location, merged.ID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,143 @@
+package smartcontract
+
+import (
+	"testing"
+)
+
+func TestCreateStockProduct(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		owner    string
+		quantity string
+		unit     string
+		wantErr  string
+	}{
+		{"valid", "s1", "CompanyA", "100", "kg", ""},
+		{"zero quantity", "s1", "CompanyA", "0", "kg", "quantity must be positive"},
+		{"no unit", "s1", "CompanyA", "10", "", "unit of measure is required"},
+		{"unregistered owner", "s1", "Nobody", "10", "kg", "is not a registered participant"},
+		{"existing ID", "p1", "CompanyA", "10", "kg", "already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(org1, "CreateStockProduct", tt.id, "Flour", tt.owner, "", "Food", tt.quantity, tt.unit)
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			if got := l.product(tt.id); got.Quantity != 100 || got.Unit != "kg" {
+				t.Errorf("stock = %d %s, want 100 kg", got.Quantity, got.Unit)
+			}
+		})
+	}
+}
+
+func TestSplitProduct(t *testing.T) {
+	tests := []struct {
+		name          string
+		childIDs      string
+		quantities    string
+		wantErr       string
+		wantRemaining int64
+		wantStatus    string
+	}{
+		{"partial", `["c1","c2"]`, `[30,20]`, "", 50, "Manufactured"},
+		{"whole", `["c1","c2"]`, `[60,40]`, "", 0, "Split"},
+		{"too much", `["c1"]`, `[101]`, "cannot split 101 kg", 0, ""},
+		{"mismatched lengths", `["c1","c2"]`, `[1]`, "got 2 child IDs but 1 quantities", 0, ""},
+		{"duplicate child", `["c1","c1"]`, `[1,1]`, "duplicate product ID c1", 0, ""},
+		{"existing child", `["p1"]`, `[1]`, "already exists", 0, ""},
+		{"zero quantity", `["c1"]`, `[0]`, "must be positive", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "100", "kg")
+
+			_, err := l.ledger.Submit(org1, "SplitProduct", "s1", tt.childIDs, tt.quantities)
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			parent := l.product("s1")
+			if parent.Quantity != tt.wantRemaining || parent.Status != tt.wantStatus {
+				t.Errorf("parent = %d %s, want %d %s", parent.Quantity, parent.Status, tt.wantRemaining, tt.wantStatus)
+			}
+			if len(parent.Children) != 2 {
+				t.Errorf("parent children = %v, want c1 and c2", parent.Children)
+			}
+			child := l.product("c1")
+			if child.Owner != "CompanyA" || child.Unit != "kg" || len(child.Parents) != 1 || child.Parents[0] != "s1" {
+				t.Errorf("child = %+v, want CompanyA's kg stock from s1", child)
+			}
+		})
+	}
+}
+
+func TestMergeProducts(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     string
+		newID   string
+		wantErr string
+	}{
+		{"compatible", `["s1","s2"]`, "m1", ""},
+		{"one product", `["s1"]`, "m1", "at least two products"},
+		{"duplicate", `["s1","s1"]`, "m1", "duplicate product ID s1"},
+		{"different unit", `["s1","s3"]`, "m1", "is not compatible"},
+		{"existing target", `["s1","s2"]`, "p1", "already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "30", "kg")
+			l.submit(org1, "CreateStockProduct", "s2", "Flour", "CompanyA", "", "Food", "20", "kg")
+			l.submit(org1, "CreateStockProduct", "s3", "Flour", "CompanyA", "", "Food", "5", "lb")
+
+			_, err := l.ledger.Submit(org1, "MergeProducts", tt.ids, tt.newID)
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			if merged := l.product("m1"); merged.Quantity != 50 || len(merged.Parents) != 2 {
+				t.Errorf("merged = %d from %v, want 50 from s1 and s2", merged.Quantity, merged.Parents)
+			}
+			for _, id := range []string{"s1", "s2"} {
+				if source := l.product(id); source.Quantity != 0 || source.Status != "Merged" {
+					t.Errorf("%s = %d %s, want 0 Merged", id, source.Quantity, source.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestStockRequiresOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		step txStep
+	}{
+		{"split", txStep{org2, "SplitProduct", []string{"s1", `["c1"]`, `[10]`}, "not authorized to act as owner CompanyA"}},
+		{"merge", txStep{org2, "MergeProducts", []string{`["s1","s2"]`, "m1"}, "not authorized to act as owner CompanyA"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "30", "kg")
+			l.submit(org1, "CreateStockProduct", "s2", "Flour", "CompanyA", "", "Food", "20", "kg")
+
+			l.run([]txStep{tt.step})
+
+			if s1 := l.product("s1"); s1.Quantity != 30 {
+				t.Errorf("s1 quantity = %d, want 30", s1.Quantity)
+			}
+		})
+	}
+}
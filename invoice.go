@@ -0,0 +1,409 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const invoiceObjectType = "Invoice"
+
+type Payment struct {
+	Amount int64     `json:"amount"`
+	PaidAt time.Time `json:"paid_at"`
+}
+
+type Invoice struct {
+	ID              string    `json:"id"`
+	PurchaseOrderID string    `json:"purchase_order_id,omitempty" metadata:",optional"`
+	ProductIDs      []string  `json:"product_ids"`
+	Issuer          string    `json:"issuer"`
+	Debtor          string    `json:"debtor"`
+	Amount          int64     `json:"amount"`
+	Paid            int64     `json:"paid"`
+	Currency        string    `json:"currency"`
+	Status          string    `json:"status"`
+	DueDate         time.Time `json:"due_date"`
+	Overdue         bool      `json:"overdue"`
+	DisputeReason   string    `json:"dispute_reason,omitempty" metadata:",optional"`
+	Payments        []Payment `json:"payments,omitempty" metadata:",optional"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// IssueInvoice bills the buyer of a fulfilled purchase order for its agreed
+// price. It is submitted by the seller, and each purchase order can be
+// invoiced once. The products must not have changed hands since the order
+// was fulfilled, and their transfers must not already have been invoiced.
+func (s *SupplyChainContract) IssueInvoice(ctx contractapi.TransactionContextInterface, id, purchaseOrderID, dueDate string) error {
+	po, err := s.QueryPurchaseOrder(ctx, purchaseOrderID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, po.Seller, "seller"); err != nil {
+		return err
+	}
+	if po.Status != "Fulfilled" {
+		return fmt.Errorf("purchase order %s is %s, only Fulfilled orders can be invoiced", purchaseOrderID, po.Status)
+	}
+	if po.InvoiceID != "" {
+		return fmt.Errorf("purchase order %s is already invoiced by %s", purchaseOrderID, po.InvoiceID)
+	}
+
+	var productIDs []string
+	for _, line := range po.LineItems {
+		productIDs = append(productIDs, line.FulfilledBy...)
+	}
+	for _, productID := range productIDs {
+		if err := s.invoiceTransfer(ctx, productID, po.Seller, po.Buyer, id); err != nil {
+			return err
+		}
+	}
+
+	invoice, err := s.newInvoice(ctx, id, productIDs, po.Seller, po.Buyer, po.AgreedPrice, po.Currency, dueDate)
+	if err != nil {
+		return err
+	}
+	invoice.PurchaseOrderID = po.ID
+
+	po.InvoiceID = id
+	po.UpdatedAt = invoice.CreatedAt
+	if err := s.putPurchaseOrder(ctx, po); err != nil {
+		return fmt.Errorf("failed to update purchase order: %v", err)
+	}
+
+	err = s.putInvoice(ctx, invoice)
+	if err != nil {
+		return fmt.Errorf("failed to put invoice into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// IssueTransferInvoice bills debtor for products that have already been
+// transferred to it outside of a purchase order. The issuer is the
+// participant of the submitter's organization, which must be the owner each
+// product was most recently transferred from. Each transfer can be invoiced
+// once.
+func (s *SupplyChainContract) IssueTransferInvoice(ctx contractapi.TransactionContextInterface, id string, productIDs []string, debtor string, amount int64, currency, dueDate string) error {
+	if len(productIDs) == 0 {
+		return fmt.Errorf("invoice must reference at least one product")
+	}
+	issuer, err := s.submitterParticipant(ctx)
+	if err != nil {
+		return err
+	}
+	if issuer.ID == debtor {
+		return fmt.Errorf("issuer and debtor must be different")
+	}
+	seen := make(map[string]bool)
+	for _, productID := range productIDs {
+		if seen[productID] {
+			return fmt.Errorf("product %s listed more than once", productID)
+		}
+		seen[productID] = true
+
+		if err := s.invoiceTransfer(ctx, productID, issuer.ID, debtor, id); err != nil {
+			return err
+		}
+	}
+
+	invoice, err := s.newInvoice(ctx, id, productIDs, issuer.ID, debtor, amount, currency, dueDate)
+	if err != nil {
+		return err
+	}
+
+	err = s.putInvoice(ctx, invoice)
+	if err != nil {
+		return fmt.Errorf("failed to put invoice into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// DisputeInvoice is submitted by the debtor.
+func (s *SupplyChainContract) DisputeInvoice(ctx contractapi.TransactionContextInterface, id, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to dispute an invoice")
+	}
+
+	invoice, err := s.QueryInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, invoice.Debtor, "debtor"); err != nil {
+		return err
+	}
+	if invoice.Status != "Issued" && invoice.Status != "PartiallyPaid" {
+		return fmt.Errorf("invoice %s is %s and cannot be disputed", id, invoice.Status)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	invoice.Status = "Disputed"
+	invoice.DisputeReason = reason
+	invoice.UpdatedAt = txTime
+
+	err = s.putInvoice(ctx, invoice)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice: %v", err)
+	}
+
+	return nil
+}
+
+// ResolveInvoiceDispute is submitted by the issuer.
+func (s *SupplyChainContract) ResolveInvoiceDispute(ctx contractapi.TransactionContextInterface, id string) error {
+	invoice, err := s.QueryInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, invoice.Issuer, "issuer"); err != nil {
+		return err
+	}
+	if invoice.Status != "Disputed" {
+		return fmt.Errorf("invoice %s is not disputed", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	invoice.Status = "Issued"
+	if invoice.Paid > 0 {
+		invoice.Status = "PartiallyPaid"
+	}
+	invoice.DisputeReason = ""
+	invoice.UpdatedAt = txTime
+
+	err = s.putInvoice(ctx, invoice)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice: %v", err)
+	}
+
+	return nil
+}
+
+// PayInvoice records a payment, submitted either by the debtor making it or
+// by the issuer confirming its receipt.
+func (s *SupplyChainContract) PayInvoice(ctx contractapi.TransactionContextInterface, id string, amount int64) error {
+	invoice, err := s.QueryInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	isIssuer, err := s.submitterActsFor(ctx, invoice.Issuer)
+	if err != nil {
+		return err
+	}
+	if !isIssuer {
+		if err := s.requireSubmitterActsFor(ctx, invoice.Debtor, "debtor"); err != nil {
+			return err
+		}
+	}
+	return s.recordPayment(ctx, invoice, amount)
+}
+
+// SettleInvoice records the outstanding balance as paid. It is submitted by
+// the issuer.
+func (s *SupplyChainContract) SettleInvoice(ctx contractapi.TransactionContextInterface, id string) error {
+	invoice, err := s.QueryInvoice(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, invoice.Issuer, "issuer"); err != nil {
+		return err
+	}
+	return s.recordPayment(ctx, invoice, invoice.Amount-invoice.Paid)
+}
+
+func (s *SupplyChainContract) QueryInvoice(ctx contractapi.TransactionContextInterface, id string) (*Invoice, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(invoiceObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	invoiceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read invoice from ledger: %v", err)
+	}
+	if invoiceJSON == nil {
+		return nil, fmt.Errorf("the invoice with ID %s does not exist", id)
+	}
+
+	var invoice Invoice
+	err = json.Unmarshal(invoiceJSON, &invoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal invoice JSON: %v", err)
+	}
+
+	if err := s.markOverdue(ctx, &invoice); err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+// GetOutstandingReceivables returns the unsettled invoices issued by org.
+func (s *SupplyChainContract) GetOutstandingReceivables(ctx contractapi.TransactionContextInterface, org string) ([]*Invoice, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(invoiceObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var invoices []*Invoice
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var invoice Invoice
+		if err := json.Unmarshal(queryResponse.Value, &invoice); err != nil {
+			return nil, err
+		}
+		if invoice.Issuer != org || invoice.Status == "Settled" {
+			continue
+		}
+		if err := s.markOverdue(ctx, &invoice); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, &invoice)
+	}
+
+	return invoices, nil
+}
+
+func (s *SupplyChainContract) newInvoice(ctx contractapi.TransactionContextInterface, id string, productIDs []string, issuer, debtor string, amount int64, currency, dueDate string) (*Invoice, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("invoice amount must be positive, got %d", amount)
+	}
+	due, err := time.Parse(time.RFC3339, dueDate)
+	if err != nil {
+		return nil, fmt.Errorf("due date must be RFC3339: %v", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(invoiceObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	invoiceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if invoiceJSON != nil {
+		return nil, fmt.Errorf("invoice with ID %s already exists", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Invoice{
+		ID:         id,
+		ProductIDs: productIDs,
+		Issuer:     issuer,
+		Debtor:     debtor,
+		Amount:     amount,
+		Currency:   currency,
+		Status:     "Issued",
+		DueDate:    due,
+		CreatedAt:  txTime,
+		UpdatedAt:  txTime,
+	}, nil
+}
+
+// invoiceTransfer marks the most recent transfer of a product, which must be
+// from issuer to debtor, as billed by invoice id.
+func (s *SupplyChainContract) invoiceTransfer(ctx contractapi.TransactionContextInterface, productID, issuer, debtor, id string) error {
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+	transfer, err := s.queryTransfer(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if transfer == nil || transfer.From != issuer || transfer.To != debtor {
+		return fmt.Errorf("product %s was not last transferred from %s to %s", productID, issuer, debtor)
+	}
+	if transfer.InvoiceID != "" {
+		return fmt.Errorf("the transfer of product %s to %s is already invoiced by %s", productID, debtor, transfer.InvoiceID)
+	}
+
+	transfer.InvoiceID = id
+	return s.putTransfer(ctx, transfer)
+}
+
+func (s *SupplyChainContract) recordPayment(ctx contractapi.TransactionContextInterface, invoice *Invoice, amount int64) error {
+	if invoice.Status != "Issued" && invoice.Status != "PartiallyPaid" {
+		return fmt.Errorf("invoice %s is %s and cannot accept payments", invoice.ID, invoice.Status)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("payment amount must be positive, got %d", amount)
+	}
+	if invoice.Paid+amount > invoice.Amount {
+		return fmt.Errorf("payment of %d exceeds outstanding balance %d on invoice %s", amount, invoice.Amount-invoice.Paid, invoice.ID)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	invoice.Paid += amount
+	invoice.Payments = append(invoice.Payments, Payment{Amount: amount, PaidAt: txTime})
+	invoice.Status = "PartiallyPaid"
+	if invoice.Paid == invoice.Amount {
+		invoice.Status = "Settled"
+		invoice.Overdue = false
+	}
+	invoice.UpdatedAt = txTime
+
+	err = s.putInvoice(ctx, invoice)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice: %v", err)
+	}
+
+	return nil
+}
+
+// markOverdue evaluates the due date against the transaction timestamp so
+// every endorser reaches the same answer.
+func (s *SupplyChainContract) markOverdue(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	if invoice.Status == "Settled" {
+		invoice.Overdue = false
+		return nil
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	invoice.Overdue = txTime.After(invoice.DueDate)
+	return nil
+}
+
+func (s *SupplyChainContract) putInvoice(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	key, err := ctx.GetStub().CreateCompositeKey(invoiceObjectType, []string{invoice.ID})
+	if err != nil {
+		return err
+	}
+
+	invoiceJSON, err := marshalState(invoice)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, invoiceJSON)
+}
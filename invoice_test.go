@@ -0,0 +1,168 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+// newInvoicedLedger returns a ledger on which CompanyA has bought p2 from
+// CompanyB through purchase order po1, invoiced by CompanyB as inv1 for 500.
+func newInvoicedLedger(t *testing.T) *testLedger {
+	l := newTestLedger(t)
+	l.submit(org1, "CreatePurchaseOrder", "po1", "CompanyA", "CompanyB", smartphoneOrder, "500", "EUR")
+	l.submit(org2, "AcceptPurchaseOrder", "po1")
+	l.submit(org2, "FulfilPurchaseOrder", "po1", `["p2"]`, `["l1"]`)
+	l.submit(org2, "IssueInvoice", "inv1", "po1", "2099-01-01T00:00:00Z")
+	return l
+}
+
+// newSoldLedger returns a ledger on which CompanyB has sold p2 to CompanyA.
+func newSoldLedger(t *testing.T) *testLedger {
+	l := newTestLedger(t)
+	l.submit(org2, "TransferOwnership", "p2", "CompanyA")
+	return l
+}
+
+func TestIssueInvoice(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org1, "CreatePurchaseOrder", "po1", "CompanyA", "CompanyB", smartphoneOrder, "500", "EUR")
+	l.submit(org2, "AcceptPurchaseOrder", "po1")
+	l.submit(org2, "FulfilPurchaseOrder", "po1", `["p2"]`, `["l1"]`)
+
+	l.run([]txStep{
+		{org1, "IssueInvoice", []string{"inv1", "po1", "2099-01-01T00:00:00Z"}, "not authorized to act as seller CompanyB"},
+		{org2, "IssueInvoice", []string{"inv1", "po1", "tomorrow"}, "due date must be RFC3339"},
+		{org2, "IssueInvoice", []string{"inv1", "po1", "2099-01-01T00:00:00Z"}, ""},
+		{org2, "IssueInvoice", []string{"inv2", "po1", "2099-01-01T00:00:00Z"}, "already invoiced by inv1"},
+	})
+
+	var invoice Invoice
+	l.evaluate(org2, &invoice, "QueryInvoice", "inv1")
+	if invoice.Issuer != "CompanyB" || invoice.Debtor != "CompanyA" || invoice.Amount != 500 {
+		t.Errorf("invoice = %+v, want CompanyB billing CompanyA 500", invoice)
+	}
+}
+
+func TestIssueTransferInvoice(t *testing.T) {
+	tests := []struct {
+		name       string
+		submitter  mockledger.Identity
+		productIDs string
+		debtor     string
+		wantErr    string
+	}{
+		{"issuer is the submitter", org2, `["p2"]`, "CompanyA", ""},
+		{"product never transferred", org1, `["p1"]`, "CompanyB", "was not last transferred from CompanyA to CompanyB"},
+		{"issuer was not the previous owner", org1, `["p2"]`, "CompanyB", "was not last transferred from CompanyA to CompanyB"},
+		{"duplicate product", org2, `["p2","p2"]`, "CompanyA", "listed more than once"},
+		{"self-billing", org1, `["p2"]`, "CompanyA", "must be different"},
+		{"unregistered submitter", org3, `["p2"]`, "CompanyA", "no active participant is registered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newSoldLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "IssueTransferInvoice", "inv1", tt.productIDs, tt.debtor, "100", "EUR", "2099-01-01T00:00:00Z")
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			var invoice Invoice
+			l.evaluate(org2, &invoice, "QueryInvoice", "inv1")
+			if invoice.Issuer != "CompanyB" || invoice.Debtor != tt.debtor {
+				t.Errorf("invoice = %s billing %s, want CompanyB billing %s", invoice.Issuer, invoice.Debtor, tt.debtor)
+			}
+		})
+	}
+}
+
+func TestTransferInvoicedOnce(t *testing.T) {
+	t.Run("transfer invoice", func(t *testing.T) {
+		l := newSoldLedger(t)
+		l.submit(org2, "IssueTransferInvoice", "inv1", `["p2"]`, "CompanyA", "100", "EUR", "2099-01-01T00:00:00Z")
+		_, err := l.ledger.Submit(org2, "IssueTransferInvoice", "inv2", `["p2"]`, "CompanyA", "100", "EUR", "2099-01-01T00:00:00Z")
+		expectError(t, err, "already invoiced by inv1")
+	})
+
+	t.Run("purchase order invoice", func(t *testing.T) {
+		l := newInvoicedLedger(t)
+		_, err := l.ledger.Submit(org2, "IssueTransferInvoice", "inv2", `["p2"]`, "CompanyA", "100", "EUR", "2099-01-01T00:00:00Z")
+		expectError(t, err, "already invoiced by inv1")
+	})
+}
+
+func TestInvoicePayments(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []txStep
+		wantStatus string
+		wantPaid   int64
+	}{
+		{"debtor pays in part", []txStep{{org1, "PayInvoice", []string{"200"}, ""}}, "PartiallyPaid", 200},
+		{"issuer confirms receipt", []txStep{{org2, "PayInvoice", []string{"500"}, ""}}, "Settled", 500},
+		{"outsider cannot pay", []txStep{{org3, "PayInvoice", []string{"100"}, "not authorized to act as debtor CompanyA"}}, "Issued", 0},
+		{"overpayment", []txStep{{org1, "PayInvoice", []string{"501"}, "exceeds outstanding balance"}}, "Issued", 0},
+		{"issuer settles", []txStep{{org1, "PayInvoice", []string{"100"}, ""}, {org2, "SettleInvoice", nil, ""}}, "Settled", 500},
+		{"debtor cannot settle", []txStep{{org1, "SettleInvoice", nil, "not authorized to act as issuer CompanyB"}}, "Issued", 0},
+		{"debtor disputes, issuer resolves", []txStep{{org1, "DisputeInvoice", []string{"damaged"}, ""}, {org2, "ResolveInvoiceDispute", nil, ""}}, "Issued", 0},
+		{"issuer cannot dispute", []txStep{{org2, "DisputeInvoice", []string{"damaged"}, "not authorized to act as debtor CompanyA"}}, "Issued", 0},
+		{"debtor cannot resolve", []txStep{{org1, "DisputeInvoice", []string{"damaged"}, ""}, {org1, "ResolveInvoiceDispute", nil, "not authorized to act as issuer CompanyB"}}, "Disputed", 0},
+		{"disputed invoice takes no payment", []txStep{{org1, "DisputeInvoice", []string{"damaged"}, ""}, {org1, "PayInvoice", []string{"100"}, "cannot accept payments"}}, "Disputed", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newInvoicedLedger(t)
+			l.run(tt.steps, "inv1")
+
+			var invoice Invoice
+			l.evaluate(org1, &invoice, "QueryInvoice", "inv1")
+			if invoice.Status != tt.wantStatus || invoice.Paid != tt.wantPaid {
+				t.Errorf("invoice = %s paid %d, want %s paid %d", invoice.Status, invoice.Paid, tt.wantStatus, tt.wantPaid)
+			}
+		})
+	}
+}
+
+func TestGetOutstandingReceivables(t *testing.T) {
+	l := newInvoicedLedger(t)
+
+	var receivables []*Invoice
+	l.evaluate(org2, &receivables, "GetOutstandingReceivables", "CompanyB")
+	if len(receivables) != 1 || receivables[0].ID != "inv1" {
+		t.Fatalf("receivables = %v, want inv1", receivables)
+	}
+
+	l.submit(org2, "SettleInvoice", "inv1")
+	receivables = nil
+	l.evaluate(org2, &receivables, "GetOutstandingReceivables", "CompanyB")
+	if len(receivables) != 0 {
+		t.Errorf("receivables after settlement = %v, want none", receivables)
+	}
+}
+
+func TestLegacyInvoiceTimestamps(t *testing.T) {
+	tests := []struct {
+		name        string
+		dueDate     string
+		wantOverdue bool
+	}{
+		{"due in the past", "2020-01-01T00:00:00Z", true},
+		{"due in the future", "2099-01-01T00:00:00Z", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.ledger.PutState("\x00Invoice\x00inv9\x00", []byte(`{"id":"inv9","product_ids":["p2"],"issuer":"CompanyB","debtor":"CompanyA","amount":500,"paid":0,"currency":"EUR","status":"Issued","due_date":"`+tt.dueDate+`","overdue":false,"payments":[{"amount":1,"paid_at":"2019-06-01T12:00:00Z"}],"created_at":"2019-06-01T12:00:00Z","updated_at":"2019-06-01T12:00:00Z"}`))
+
+			var invoice Invoice
+			l.evaluate(org1, &invoice, "QueryInvoice", "inv9")
+			if invoice.Overdue != tt.wantOverdue || invoice.CreatedAt.Year() != 2019 || invoice.Payments[0].PaidAt.Month() != 6 {
+				t.Errorf("invoice = %+v, want overdue %t with 2019 timestamps", invoice, tt.wantOverdue)
+			}
+		})
+	}
+}
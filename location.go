@@ -0,0 +1,306 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	locationObjectType        = "Location"
+	productLocationObjectType = "ProductLocation"
+	custodyEventObjectType    = "CustodyEvent"
+)
+
+type Location struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	GLN       string    `json:"gln,omitempty" metadata:",optional"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Operator  string    `json:"operator"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CustodyEvent struct {
+	ProductID  string    `json:"product_id"`
+	LocationID string    `json:"location_id"`
+	Action     string    `json:"action"`
+	Owner      string    `json:"owner"`
+	TxID       string    `json:"tx_id"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+var locationTypes = []string{"warehouse", "plant", "store", "distribution_center", "port"}
+
+func (s *SupplyChainContract) RegisterLocation(ctx contractapi.TransactionContextInterface, id, name, locationType, gln string, latitude, longitude float64, operator string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if !containsString(locationTypes, locationType) {
+		return fmt.Errorf("location type must be one of %v, got %q", locationTypes, locationType)
+	}
+	if gln != "" {
+		if err := validateGS1Number("GLN", gln, 13); err != nil {
+			return err
+		}
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("coordinates %f,%f are out of range", latitude, longitude)
+	}
+	if err := s.requireActiveParticipant(ctx, operator); err != nil {
+		return err
+	}
+
+	exists, err := s.LocationExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("location with ID %s already exists", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	location := Location{
+		ID:        id,
+		Name:      name,
+		Type:      locationType,
+		GLN:       gln,
+		Latitude:  latitude,
+		Longitude: longitude,
+		Operator:  operator,
+		CreatedAt: txTime,
+		UpdatedAt: txTime,
+	}
+
+	err = s.putLocation(ctx, &location)
+	if err != nil {
+		return fmt.Errorf("failed to put location into ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) QueryLocation(ctx contractapi.TransactionContextInterface, id string) (*Location, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(locationObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	locationJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read location from ledger: %v", err)
+	}
+	if locationJSON == nil {
+		return nil, fmt.Errorf("the location with ID %s does not exist", id)
+	}
+
+	var location Location
+	err = json.Unmarshal(locationJSON, &location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal location JSON: %v", err)
+	}
+
+	return &location, nil
+}
+
+func (s *SupplyChainContract) LocationExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(locationObjectType, []string{id})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	locationJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return locationJSON != nil, nil
+}
+
+// CheckIn records that a product has arrived at a location. A product must be
+// checked out of its previous location first.
+func (s *SupplyChainContract) CheckIn(ctx contractapi.TransactionContextInterface, productID, locationID string) error {
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "owner"); err != nil {
+		return err
+	}
+	if product.Location != "" {
+		return fmt.Errorf("product %s is still checked in at %s", productID, product.Location)
+	}
+	if _, err := s.QueryLocation(ctx, locationID); err != nil {
+		return err
+	}
+
+	product.Location = locationID
+	return s.recordCustodyEvent(ctx, product, locationID, "CheckIn")
+}
+
+// CheckOut records that a product has left its current location and is in
+// transit.
+func (s *SupplyChainContract) CheckOut(ctx contractapi.TransactionContextInterface, productID, locationID string) error {
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "owner"); err != nil {
+		return err
+	}
+	if product.Location != locationID {
+		return fmt.Errorf("product %s is not checked in at %s", productID, locationID)
+	}
+
+	product.Location = ""
+	return s.recordCustodyEvent(ctx, product, locationID, "CheckOut")
+}
+
+func (s *SupplyChainContract) GetProductsAtLocation(ctx contractapi.TransactionContextInterface, locationID string) ([]*Product, error) {
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(productLocationObjectType, []string{locationID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var products []*Product
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		product, err := s.readProduct(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, viewer.project(product))
+	}
+
+	return products, nil
+}
+
+// GetProductLocationTrail returns a product's check-in and check-out events
+// in chronological order. It is not visible to public callers, and the owner
+// at each event is shown only to that owner and to auditors.
+func (s *SupplyChainContract) GetProductLocationTrail(ctx contractapi.TransactionContextInterface, productID string) ([]*CustodyEvent, error) {
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if viewer.viewOf(product.Owner) == viewPublic {
+		return nil, fmt.Errorf("the location trail of product %s is only visible to registered participants", productID)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(custodyEventObjectType, []string{productID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var events []*CustodyEvent
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var event CustodyEvent
+		if err := json.Unmarshal(queryResponse.Value, &event); err != nil {
+			return nil, err
+		}
+		if view := viewer.viewOf(event.Owner); view != viewOwner && view != viewAuditor {
+			event.Owner = ""
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+func (s *SupplyChainContract) recordCustodyEvent(ctx contractapi.TransactionContextInterface, product *Product, locationID, action string) error {
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	product.UpdatedAt = txTime
+	if err := s.putProduct(ctx, product); err != nil {
+		return fmt.Errorf("failed to update product: %v", err)
+	}
+
+	if err := setLocationIndex(ctx, locationID, product.ID, action == "CheckIn"); err != nil {
+		return err
+	}
+
+	event := CustodyEvent{
+		ProductID:  product.ID,
+		LocationID: locationID,
+		Action:     action,
+		Owner:      product.Owner,
+		TxID:       ctx.GetStub().GetTxID(),
+		Timestamp:  txTime,
+	}
+
+	// Keying by the zero-padded transaction time keeps the trail in order.
+	eventKey, err := ctx.GetStub().CreateCompositeKey(custodyEventObjectType, []string{product.ID, fmt.Sprintf("%020d", txTime.UnixNano()), event.TxID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	eventJSON, err := marshalState(event)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(eventKey, eventJSON)
+}
+
+// setLocationIndex adds a product to or removes it from the index of products
+// at a location.
+func setLocationIndex(ctx contractapi.TransactionContextInterface, locationID, productID string, present bool) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(productLocationObjectType, []string{locationID, productID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if present {
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	} else {
+		err = ctx.GetStub().DelState(indexKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update location index: %v", err)
+	}
+	return nil
+}
+
+func (s *SupplyChainContract) putLocation(ctx contractapi.TransactionContextInterface, location *Location) error {
+	key, err := ctx.GetStub().CreateCompositeKey(locationObjectType, []string{location.ID})
+	if err != nil {
+		return err
+	}
+
+	locationJSON, err := marshalState(location)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, locationJSON)
+}
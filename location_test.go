@@ -0,0 +1,236 @@
+package smartcontract
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRegisterLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"valid", []string{"w1", "Main Warehouse", "warehouse", "0614141000005", "52.5", "13.4", "CompanyA"}, ""},
+		{"unknown type", []string{"w1", "Main Warehouse", "depot", "", "52.5", "13.4", "CompanyA"}, "location type must be one of"},
+		{"bad GLN", []string{"w1", "Main Warehouse", "warehouse", "0614141000006", "52.5", "13.4", "CompanyA"}, "has check digit 6, expected 5"},
+		{"bad coordinates", []string{"w1", "Main Warehouse", "warehouse", "", "95", "13.4", "CompanyA"}, "are out of range"},
+		{"unregistered operator", []string{"w1", "Main Warehouse", "warehouse", "", "52.5", "13.4", "Nobody"}, "is not a registered participant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(admin, "RegisterLocation", tt.args...)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+
+	l := newTestLedger(t)
+	l.run([]txStep{
+		{org1, "RegisterLocation", []string{"w1", "Main Warehouse", "warehouse", "", "52.5", "13.4", "CompanyA"}, "admin role required"},
+		{admin, "RegisterLocation", []string{"w1", "Main Warehouse", "warehouse", "", "52.5", "13.4", "CompanyA"}, ""},
+		{admin, "RegisterLocation", []string{"w1", "Main Warehouse", "warehouse", "", "52.5", "13.4", "CompanyA"}, "location with ID w1 already exists"},
+	})
+}
+
+// newLocationLedger registers warehouses w1 and w2 and stock product s1 of
+// 100 kg checked in at w1.
+func newLocationLedger(t *testing.T) *testLedger {
+	t.Helper()
+	l := newTestLedger(t)
+	l.submit(admin, "RegisterLocation", "w1", "Main Warehouse", "warehouse", "", "52.5", "13.4", "CompanyA")
+	l.submit(admin, "RegisterLocation", "w2", "Overflow Warehouse", "warehouse", "", "52.4", "13.5", "CompanyA")
+	l.submit(org1, "CreateStockProduct", "s1", "Flour", "CompanyA", "", "Food", "100", "kg")
+	l.submit(org1, "CheckIn", "s1", "w1")
+	return l
+}
+
+// productsAt returns the sorted IDs of the products at a location.
+func (l *testLedger) productsAt(locationID string) []string {
+	l.t.Helper()
+	var products []*Product
+	l.evaluate(auditor, &products, "GetProductsAtLocation", locationID)
+	ids := []string{}
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestCheckInAndOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []txStep
+		wantW1  []string
+		wantW2  []string
+		wantLoc string
+	}{
+		{"checked in", nil, []string{"s1"}, []string{}, "w1"},
+		{
+			name:    "checked out",
+			steps:   []txStep{{org1, "CheckOut", []string{"s1", "w1"}, ""}},
+			wantW1:  []string{},
+			wantW2:  []string{},
+			wantLoc: "",
+		},
+		{
+			name: "moved",
+			steps: []txStep{
+				{org1, "CheckOut", []string{"s1", "w1"}, ""},
+				{org1, "CheckIn", []string{"s1", "w2"}, ""},
+			},
+			wantW1:  []string{},
+			wantW2:  []string{"s1"},
+			wantLoc: "w2",
+		},
+		{
+			name:    "still checked in",
+			steps:   []txStep{{org1, "CheckIn", []string{"s1", "w2"}, "product s1 is still checked in at w1"}},
+			wantW1:  []string{"s1"},
+			wantW2:  []string{},
+			wantLoc: "w1",
+		},
+		{
+			name:    "checked out elsewhere",
+			steps:   []txStep{{org1, "CheckOut", []string{"s1", "w2"}, "product s1 is not checked in at w2"}},
+			wantW1:  []string{"s1"},
+			wantW2:  []string{},
+			wantLoc: "w1",
+		},
+		{
+			name:    "checked out by another organization",
+			steps:   []txStep{{org2, "CheckOut", []string{"s1", "w1"}, "submitter Org2MSP is not authorized to act as owner CompanyA"}},
+			wantW1:  []string{"s1"},
+			wantW2:  []string{},
+			wantLoc: "w1",
+		},
+		{
+			name: "checked in by another organization",
+			steps: []txStep{
+				{org1, "CheckOut", []string{"s1", "w1"}, ""},
+				{org2, "CheckIn", []string{"s1", "w2"}, "submitter Org2MSP is not authorized to act as owner CompanyA"},
+			},
+			wantW1:  []string{},
+			wantW2:  []string{},
+			wantLoc: "",
+		},
+		{
+			name: "unknown location",
+			steps: []txStep{
+				{org1, "CheckOut", []string{"s1", "w1"}, ""},
+				{org1, "CheckIn", []string{"s1", "w9"}, "the location with ID w9 does not exist"},
+			},
+			wantW1:  []string{},
+			wantW2:  []string{},
+			wantLoc: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLocationLedger(t)
+			l.run(tt.steps)
+
+			if got := l.productsAt("w1"); !reflect.DeepEqual(got, tt.wantW1) {
+				t.Errorf("products at w1 = %v, want %v", got, tt.wantW1)
+			}
+			if got := l.productsAt("w2"); !reflect.DeepEqual(got, tt.wantW2) {
+				t.Errorf("products at w2 = %v, want %v", got, tt.wantW2)
+			}
+			if got := l.product("s1").Location; got != tt.wantLoc {
+				t.Errorf("s1 location = %q, want %q", got, tt.wantLoc)
+			}
+		})
+	}
+}
+
+func TestLocationOfSplitAndMergedProducts(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []txStep
+		wantW1    []string
+		locations map[string]string
+	}{
+		{
+			name:      "partial split",
+			steps:     []txStep{{org1, "SplitProduct", []string{"s1", `["c1","c2"]`, `[30,20]`}, ""}},
+			wantW1:    []string{"c1", "c2", "s1"},
+			locations: map[string]string{"s1": "w1", "c1": "w1", "c2": "w1"},
+		},
+		{
+			name:      "whole split",
+			steps:     []txStep{{org1, "SplitProduct", []string{"s1", `["c1","c2"]`, `[60,40]`}, ""}},
+			wantW1:    []string{"c1", "c2"},
+			locations: map[string]string{"s1": "", "c1": "w1", "c2": "w1"},
+		},
+		{
+			name: "merged at one location",
+			steps: []txStep{
+				{org1, "SplitProduct", []string{"s1", `["c1","c2"]`, `[60,40]`}, ""},
+				{org1, "MergeProducts", []string{`["c1","c2"]`, "m1"}, ""},
+			},
+			wantW1:    []string{"m1"},
+			locations: map[string]string{"c1": "", "c2": "", "m1": "w1"},
+		},
+		{
+			name: "merged across locations",
+			steps: []txStep{
+				{org1, "SplitProduct", []string{"s1", `["c1","c2"]`, `[60,40]`}, ""},
+				{org1, "CheckOut", []string{"c2", "w1"}, ""},
+				{org1, "CheckIn", []string{"c2", "w2"}, ""},
+				{org1, "MergeProducts", []string{`["c1","c2"]`, "m1"}, ""},
+			},
+			wantW1:    []string{},
+			locations: map[string]string{"c1": "", "c2": "", "m1": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLocationLedger(t)
+			l.run(tt.steps)
+
+			if got := l.productsAt("w1"); !reflect.DeepEqual(got, tt.wantW1) {
+				t.Errorf("products at w1 = %v, want %v", got, tt.wantW1)
+			}
+			if got := l.productsAt("w2"); len(got) != 0 {
+				t.Errorf("products at w2 = %v, want none", got)
+			}
+			for id, want := range tt.locations {
+				if got := l.product(id).Location; got != want {
+					t.Errorf("%s location = %q, want %q", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetProductLocationTrail(t *testing.T) {
+	l := newLocationLedger(t)
+	l.submit(org1, "CheckOut", "s1", "w1")
+	l.submit(org1, "CheckIn", "s1", "w2")
+
+	var events []*CustodyEvent
+	l.evaluate(org1, &events, "GetProductLocationTrail", "s1")
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Action+" "+event.LocationID+" "+event.Owner)
+	}
+	want := []string{"CheckIn w1 CompanyA", "CheckOut w1 CompanyA", "CheckIn w2 CompanyA"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("owner's trail = %v, want %v", got, want)
+	}
+
+	l.evaluate(org2, &events, "GetProductLocationTrail", "s1")
+	for _, event := range events {
+		if event.Owner != "" {
+			t.Errorf("partner's trail shows owner %s", event.Owner)
+		}
+	}
+
+	_, err := l.ledger.Evaluate(org3, "GetProductLocationTrail", "s1")
+	expectError(t, err, "only visible to registered participants")
+}
@@ -0,0 +1,202 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const migrationObjectType = "Migration"
+
+// productSchemaVersion is the version writeProduct stamps on every product.
+// Records written before versioning was introduced carry no version and are
+// read as version 1. Bump it, and add a step to upgradeProduct, whenever a
+// field is added whose zero value is not a correct default.
+//
+//	1: unversioned; products predating stock tracking have no quantity or unit
+//	2: quantity and unit are always set
+const productSchemaVersion = 2
+
+type MigrationProgress struct {
+	TargetVersion int       `json:"target_version"`
+	NextKey       string    `json:"next_key"`
+	Scanned       int       `json:"scanned"`
+	Migrated      int       `json:"migrated"`
+	Completed     bool      `json:"completed"`
+	StartedAt     time.Time `json:"started_at"`
+	UpdatedBy     string    `json:"updated_by"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// unmarshalProduct decodes a stored product and upgrades it to the current
+// schema. Every read of product state goes through it, so the rest of the
+// contract only ever sees current-version products.
+func unmarshalProduct(productJSON []byte) (*Product, error) {
+	var product Product
+	if err := json.Unmarshal(productJSON, &product); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal product JSON: %v", err)
+	}
+	if err := upgradeProduct(&product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// upgradeProduct applies each upgrade step from the product's stored version
+// to productSchemaVersion in turn.
+func upgradeProduct(product *Product) error {
+	if product.SchemaVersion == 0 {
+		product.SchemaVersion = 1
+	}
+	if product.SchemaVersion > productSchemaVersion {
+		return fmt.Errorf("product %s has schema version %d, which is newer than this contract's version %d", product.ID, product.SchemaVersion, productSchemaVersion)
+	}
+
+	if product.SchemaVersion < 2 {
+		if product.Quantity == 0 && product.Unit == "" {
+			product.Quantity = 1
+		}
+		if product.Unit == "" {
+			product.Unit = "each"
+		}
+		product.SchemaVersion = 2
+	}
+
+	return nil
+}
+
+// MigrateProducts scans up to pageSize stored products, rewriting those that
+// predate the current schema version, continuing from where the previous call
+// stopped, and returns the accumulated progress. The progress record keeps
+// the next key to scan rather than a query bookmark, since Fabric allows
+// paginated queries only in read-only transactions. Call it repeatedly until
+// Completed is set; once a pass has completed, calls return its progress
+// until a later schema bump starts a new pass. Rewriting a product is subject
+// to its key-level endorsement policy, so the transaction must be endorsed by
+// the owning organizations of the products in the page.
+//
+// Products are rewritten with writeProduct, which pins their endorsement
+// policy like any other write, but not through putProduct: an upgrade only
+// changes how a product is encoded, not what it records, so it is exempt
+// from holds, and frozen products are migrated with the rest.
+func (s *SupplyChainContract) MigrateProducts(ctx contractapi.TransactionContextInterface, pageSize int32) (*MigrationProgress, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	progress, err := s.GetMigrationProgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if progress.Completed && progress.TargetVersion == productSchemaVersion {
+		return progress, nil
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if progress.StartedAt.IsZero() || progress.TargetVersion < productSchemaVersion {
+		progress = &MigrationProgress{TargetVersion: productSchemaVersion, StartedAt: txTime}
+	}
+
+	pageSize, err = s.pageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(progress.NextKey, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	scanned := int32(0)
+	progress.NextKey = ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if scanned == pageSize {
+			progress.NextKey = queryResponse.Key
+			break
+		}
+		scanned++
+		progress.Scanned++
+
+		var stored struct {
+			SchemaVersion int `json:"schema_version"`
+		}
+		if err := json.Unmarshal(queryResponse.Value, &stored); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal product JSON: %v", err)
+		}
+		if stored.SchemaVersion >= productSchemaVersion {
+			continue
+		}
+
+		product, err := unmarshalProduct(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		mspID, err := s.ownerMSPID(ctx, product.Owner)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.writeProduct(ctx, product, mspID); err != nil {
+			return nil, fmt.Errorf("failed to rewrite product %s: %v", product.ID, err)
+		}
+		progress.Migrated++
+	}
+
+	progress.Completed = progress.NextKey == ""
+	progress.UpdatedBy = submitter
+	progress.UpdatedAt = txTime
+
+	key, err := ctx.GetStub().CreateCompositeKey(migrationObjectType, []string{"products"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	progressJSON, err := marshalState(progress)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(key, progressJSON); err != nil {
+		return nil, fmt.Errorf("failed to put migration progress into ledger: %v", err)
+	}
+
+	if err := ctx.GetStub().SetEvent("ProductsMigrated", progressJSON); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// GetMigrationProgress returns the state of the current or most recent
+// product migration pass.
+func (s *SupplyChainContract) GetMigrationProgress(ctx contractapi.TransactionContextInterface) (*MigrationProgress, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(migrationObjectType, []string{"products"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	progressJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration progress from ledger: %v", err)
+	}
+	if progressJSON == nil {
+		return &MigrationProgress{TargetVersion: productSchemaVersion}, nil
+	}
+
+	var progress MigrationProgress
+	if err := json.Unmarshal(progressJSON, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal migration progress JSON: %v", err)
+	}
+
+	return &progress, nil
+}
@@ -0,0 +1,120 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// seedLegacyProducts writes products as stored before schema versioning,
+// without a quantity or unit, under keys that sort before p1 and p2.
+func (l *testLedger) seedLegacyProducts(ids ...string) {
+	for _, id := range ids {
+		l.ledger.PutState(id, []byte(`{"id":"`+id+`","name":"Bolt","status":"Manufactured","owner":"CompanyA","category":"Hardware"}`))
+	}
+}
+
+func (l *testLedger) migrate(pageSize string) *MigrationProgress {
+	l.t.Helper()
+	var progress MigrationProgress
+	if err := json.Unmarshal(l.submit(admin, "MigrateProducts", pageSize), &progress); err != nil {
+		l.t.Fatalf("failed to decode migration progress: %v", err)
+	}
+	return &progress
+}
+
+func TestMigrateProducts(t *testing.T) {
+	l := newTestLedger(t)
+	l.seedLegacyProducts("old1", "old2", "old3")
+
+	tests := []struct {
+		name      string
+		scanned   int
+		migrated  int
+		nextKey   string
+		completed bool
+	}{
+		{"first page", 2, 2, "old3", false},
+		{"second page", 4, 3, "p2", false},
+		{"last page", 5, 3, "", true},
+		{"completed pass", 5, 3, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := l.migrate("2")
+			if progress.Scanned != tt.scanned || progress.Migrated != tt.migrated || progress.NextKey != tt.nextKey || progress.Completed != tt.completed {
+				t.Errorf("progress = %+v, want %d scanned, %d migrated, next key %q, completed %t", progress, tt.scanned, tt.migrated, tt.nextKey, tt.completed)
+			}
+		})
+	}
+
+	var stored MigrationProgress
+	l.evaluate(org1, &stored, "GetMigrationProgress")
+	if !stored.Completed || stored.Migrated != 3 || stored.UpdatedBy != "Org1MSP" {
+		t.Errorf("stored progress = %+v, want a completed pass of 3 products by Org1MSP", stored)
+	}
+
+	var history []*ProductHistoryEntry
+	l.evaluate(auditor, &history, "GetProductHistory", "old1")
+	if len(history) != 1 || history[0].Product.Quantity != 1 || history[0].Product.Unit != "each" {
+		t.Errorf("old1 history = %+v, want one rewrite of 1 each", history)
+	}
+}
+
+func TestMigrateProductsRequiresAdmin(t *testing.T) {
+	l := newTestLedger(t)
+	_, err := l.ledger.Submit(org1, "MigrateProducts", "10")
+	expectError(t, err, "admin role required")
+}
+
+func TestUpgradeProduct(t *testing.T) {
+	tests := []struct {
+		name         string
+		stored       string
+		wantQuantity int64
+		wantUnit     string
+		wantErr      string
+	}{
+		{"unversioned", `{"id":"p"}`, 1, "each", ""},
+		{"unversioned stock", `{"id":"p","quantity":5,"unit":"kg"}`, 5, "kg", ""},
+		{"unversioned without unit", `{"id":"p","quantity":5}`, 5, "each", ""},
+		{"current", `{"id":"p","quantity":0,"unit":"kg","schema_version":2}`, 0, "kg", ""},
+		{"newer", `{"id":"p","schema_version":3}`, 0, "", "newer than this contract's version 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, err := unmarshalProduct([]byte(tt.stored))
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			if product.Quantity != tt.wantQuantity || product.Unit != tt.wantUnit || product.SchemaVersion != productSchemaVersion {
+				t.Errorf("product = %d %s version %d, want %d %s version %d", product.Quantity, product.Unit, product.SchemaVersion, tt.wantQuantity, tt.wantUnit, productSchemaVersion)
+			}
+		})
+	}
+}
+
+func TestMigrateFrozenProduct(t *testing.T) {
+	l := newTestLedger(t)
+	l.seedLegacyProducts("old1")
+	l.submit(compliance, "FreezeAssets", "product", "old1", "contamination")
+
+	if progress := l.migrate("10"); progress.Migrated != 1 || !progress.Completed {
+		t.Errorf("progress = %+v, want old1 migrated in a completed pass", progress)
+	}
+	if product := l.product("old1"); product.SchemaVersion != productSchemaVersion || product.Unit != "each" {
+		t.Errorf("old1 = %+v, want it upgraded to schema version %d", product, productSchemaVersion)
+	}
+
+	var orgs []string
+	l.evaluate(org1, &orgs, "GetProductEndorsementPolicy", "old1")
+	if !reflect.DeepEqual(orgs, []string{"Org1MSP"}) {
+		t.Errorf("endorsing organizations = %v, want [Org1MSP]", orgs)
+	}
+
+	_, err := l.ledger.Submit(org1, "TransferOwnership", "old1", "CompanyB")
+	expectError(t, err, "product old1 is frozen by a hold on product old1")
+}
@@ -0,0 +1,286 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const participantObjectType = "Participant"
+
+type Participant struct {
+	ID        string    `json:"id"`
+	LegalName string    `json:"legal_name"`
+	MSPID     string    `json:"msp_id"`
+	Role      string    `json:"role"`
+	Contact   string    `json:"contact"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (s *SupplyChainContract) RegisterParticipant(ctx contractapi.TransactionContextInterface, id, legalName, mspID, role, contact string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if id == "" || mspID == "" {
+		return fmt.Errorf("participant ID and MSP ID are required")
+	}
+
+	exists, err := s.ParticipantExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("participant with ID %s already exists", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	participant := Participant{
+		ID:        id,
+		LegalName: legalName,
+		MSPID:     mspID,
+		Role:      role,
+		Contact:   contact,
+		Status:    "Active",
+		CreatedAt: txTime,
+		UpdatedAt: txTime,
+	}
+
+	err = s.putParticipant(ctx, &participant)
+	if err != nil {
+		return fmt.Errorf("failed to put participant into ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) UpdateParticipant(ctx contractapi.TransactionContextInterface, id, legalName, role, contact string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	participant, err := s.QueryParticipant(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	participant.LegalName = legalName
+	participant.Role = role
+	participant.Contact = contact
+	participant.UpdatedAt = txTime
+
+	err = s.putParticipant(ctx, participant)
+	if err != nil {
+		return fmt.Errorf("failed to update participant: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) SuspendParticipant(ctx contractapi.TransactionContextInterface, id string) error {
+	return s.setParticipantStatus(ctx, id, "Suspended")
+}
+
+func (s *SupplyChainContract) ReactivateParticipant(ctx contractapi.TransactionContextInterface, id string) error {
+	return s.setParticipantStatus(ctx, id, "Active")
+}
+
+func (s *SupplyChainContract) QueryParticipant(ctx contractapi.TransactionContextInterface, id string) (*Participant, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(participantObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	participantJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read participant from ledger: %v", err)
+	}
+	if participantJSON == nil {
+		return nil, fmt.Errorf("%s is not a registered participant", id)
+	}
+
+	var participant Participant
+	err = json.Unmarshal(participantJSON, &participant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal participant JSON: %v", err)
+	}
+
+	return &participant, nil
+}
+
+func (s *SupplyChainContract) ParticipantExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(participantObjectType, []string{id})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	participantJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return participantJSON != nil, nil
+}
+
+func (s *SupplyChainContract) GetAllParticipants(ctx contractapi.TransactionContextInterface) ([]*Participant, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(participantObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var participants []*Participant
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var participant Participant
+		if err := json.Unmarshal(queryResponse.Value, &participant); err != nil {
+			return nil, err
+		}
+		participants = append(participants, &participant)
+	}
+
+	return participants, nil
+}
+
+func (s *SupplyChainContract) requireActiveParticipant(ctx contractapi.TransactionContextInterface, id string) error {
+	participant, err := s.QueryParticipant(ctx, id)
+	if err != nil {
+		return err
+	}
+	if participant.Status != "Active" {
+		return fmt.Errorf("participant %s is %s", id, participant.Status)
+	}
+	return nil
+}
+
+// requireParticipantMSP checks that mspID is the MSP ID of a registered
+// participant.
+func (s *SupplyChainContract) requireParticipantMSP(ctx contractapi.TransactionContextInterface, mspID string) error {
+	participants, err := s.GetAllParticipants(ctx)
+	if err != nil {
+		return err
+	}
+	for _, participant := range participants {
+		if participant.MSPID == mspID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not the MSP ID of a registered participant", mspID)
+}
+
+// requireSubmitterActsFor checks that the submitter belongs to the
+// organization of participant id, who takes the named part in the
+// transaction.
+func (s *SupplyChainContract) requireSubmitterActsFor(ctx contractapi.TransactionContextInterface, id, part string) error {
+	ok, err := s.submitterActsFor(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		submitter, err := s.getSubmitterOrg(ctx)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("submitter %s is not authorized to act as %s %s", submitter, part, id)
+	}
+	return nil
+}
+
+func (s *SupplyChainContract) submitterActsFor(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return false, err
+	}
+	mspID, err := s.ownerMSPID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return submitter == mspID, nil
+}
+
+// submitterParticipant resolves the submitter's organization to the active
+// participant registered under its MSP ID. It fails when there is none, or
+// more than one, as the participant acted for would then be ambiguous.
+func (s *SupplyChainContract) submitterParticipant(ctx contractapi.TransactionContextInterface) (*Participant, error) {
+	mspID, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.GetAllParticipants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Participant
+	var ids []string
+	for _, participant := range participants {
+		if participant.MSPID == mspID && participant.Status == "Active" {
+			matches = append(matches, participant)
+			ids = append(ids, participant.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("submitter %s is not authorized: no active participant is registered for it", mspID)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("submitter %s is registered as several participants %v and cannot be resolved to one", mspID, ids)
+}
+
+func (s *SupplyChainContract) setParticipantStatus(ctx contractapi.TransactionContextInterface, id, status string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	participant, err := s.QueryParticipant(ctx, id)
+	if err != nil {
+		return err
+	}
+	if participant.Status == status {
+		return fmt.Errorf("participant %s is already %s", id, status)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	participant.Status = status
+	participant.UpdatedAt = txTime
+
+	err = s.putParticipant(ctx, participant)
+	if err != nil {
+		return fmt.Errorf("failed to update participant: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) putParticipant(ctx contractapi.TransactionContextInterface, participant *Participant) error {
+	key, err := ctx.GetStub().CreateCompositeKey(participantObjectType, []string{participant.ID})
+	if err != nil {
+		return err
+	}
+
+	participantJSON, err := marshalState(participant)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, participantJSON)
+}
@@ -0,0 +1,75 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+func TestRegisterParticipant(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		id        string
+		mspID     string
+		wantErr   string
+	}{
+		{"admin", admin, "CompanyC", "Org3MSP", ""},
+		{"not admin", org3, "CompanyC", "Org3MSP", "admin role required"},
+		{"no MSP ID", admin, "CompanyC", "", "MSP ID are required"},
+		{"existing ID", admin, "CompanyA", "Org3MSP", "already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "RegisterParticipant", tt.id, "Company C", tt.mspID, "Retailer", "ops@c.example")
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+
+			var participant Participant
+			l.evaluate(org1, &participant, "QueryParticipant", tt.id)
+			if participant.MSPID != tt.mspID || participant.Status != "Active" {
+				t.Errorf("participant = %+v, want an active %s participant", participant, tt.mspID)
+			}
+		})
+	}
+}
+
+func TestParticipantStatus(t *testing.T) {
+	tests := []struct {
+		name            string
+		steps           []txStep
+		wantTransferErr string
+	}{
+		{"active", nil, ""},
+		{"suspended", []txStep{{admin, "SuspendParticipant", []string{"CompanyB"}, ""}}, "participant CompanyB is Suspended"},
+		{
+			name: "reactivated",
+			steps: []txStep{
+				{admin, "SuspendParticipant", []string{"CompanyB"}, ""},
+				{admin, "ReactivateParticipant", []string{"CompanyB"}, ""},
+			},
+		},
+		{"suspended by non-admin", []txStep{{org1, "SuspendParticipant", []string{"CompanyB"}, "admin role required"}}, ""},
+		{"unregistered", []txStep{{admin, "SuspendParticipant", []string{"Nobody"}, "is not a registered participant"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.run(tt.steps)
+
+			_, err := l.ledger.Submit(org1, "TransferOwnership", "p1", "CompanyB")
+			expectError(t, err, tt.wantTransferErr)
+		})
+	}
+}
+
+func TestCreateProductRequiresRegisteredOwner(t *testing.T) {
+	l := newTestLedger(t)
+	_, err := l.ledger.Submit(org1, "CreateProduct", "p3", "Tablet", "Nobody", "", "Electronics")
+	expectError(t, err, "Nobody is not a registered participant")
+}
@@ -0,0 +1,100 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const pauseObjectType = "Pause"
+
+type PauseState struct {
+	Paused    bool      `json:"paused"`
+	Reason    string    `json:"reason"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetPaused switches the contract in or out of maintenance mode. While
+// paused, every transaction except the read-only ones and SetPaused itself
+// is rejected.
+func (s *SupplyChainContract) SetPaused(ctx contractapi.TransactionContextInterface, paused bool, reason string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	submitter, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	state := PauseState{
+		Paused:    paused,
+		Reason:    reason,
+		UpdatedBy: submitter,
+		UpdatedAt: txTime,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(pauseObjectType, []string{"contract"})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	stateJSON, err := marshalState(state)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(key, stateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put pause state into ledger: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent("PauseChanged", stateJSON)
+}
+
+func (s *SupplyChainContract) GetPauseState(ctx contractapi.TransactionContextInterface) (*PauseState, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pauseObjectType, []string{"contract"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	stateJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pause state from ledger: %v", err)
+	}
+	if stateJSON == nil {
+		return &PauseState{}, nil
+	}
+
+	var state PauseState
+	err = json.Unmarshal(stateJSON, &state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pause state JSON: %v", err)
+	}
+
+	return &state, nil
+}
+
+// requireNotPaused rejects function while the contract is paused, unless it
+// is read-only or SetPaused itself.
+func (s *SupplyChainContract) requireNotPaused(ctx contractapi.TransactionContextInterface, function string) error {
+	if function == "SetPaused" || containsString(s.GetEvaluateTransactions(), function) {
+		return nil
+	}
+
+	state, err := s.GetPauseState(ctx)
+	if err != nil {
+		return err
+	}
+	if state.Paused {
+		return fmt.Errorf("contract is paused for maintenance, %s is unavailable: %s", function, state.Reason)
+	}
+
+	return nil
+}
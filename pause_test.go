@@ -0,0 +1,58 @@
+package smartcontract
+
+import (
+	"testing"
+)
+
+func TestPause(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []txStep
+	}{
+		{
+			name: "paused contract rejects submissions",
+			steps: []txStep{
+				{admin, "SetPaused", []string{"true", "upgrade"}, ""},
+				{org1, "TransferOwnership", []string{"p1", "CompanyB"}, "contract is paused for maintenance, TransferOwnership is unavailable: upgrade"},
+				{admin, "RegisterParticipant", []string{"CompanyC", "Company C", "Org3MSP", "Retailer", ""}, "contract is paused"},
+			},
+		},
+		{
+			name: "paused contract still answers queries",
+			steps: []txStep{
+				{admin, "SetPaused", []string{"true", "upgrade"}, ""},
+				{org1, "QueryProduct", []string{"p1"}, ""},
+			},
+		},
+		{
+			name: "unpaused",
+			steps: []txStep{
+				{admin, "SetPaused", []string{"true", "upgrade"}, ""},
+				{admin, "SetPaused", []string{"false", ""}, ""},
+				{org1, "TransferOwnership", []string{"p1", "CompanyB"}, ""},
+			},
+		},
+		{
+			name:  "not admin",
+			steps: []txStep{{org1, "SetPaused", []string{"true", "upgrade"}, "admin role required"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.run(tt.steps)
+		})
+	}
+}
+
+func TestGetPauseState(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(admin, "SetPaused", "true", "upgrade")
+
+	var state PauseState
+	l.evaluate(org2, &state, "GetPauseState")
+	if !state.Paused || state.Reason != "upgrade" || state.UpdatedBy != "Org1MSP" {
+		t.Errorf("pause state = %+v, want paused for upgrade by Org1MSP", state)
+	}
+}
@@ -0,0 +1,286 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const purchaseOrderObjectType = "PurchaseOrder"
+
+type LineItem struct {
+	ItemID      string   `json:"item_id"`
+	Name        string   `json:"name"`
+	Quantity    int64    `json:"quantity"`
+	Unit        string   `json:"unit"`
+	UnitPrice   int64    `json:"unit_price"`
+	FulfilledBy []string `json:"fulfilled_by,omitempty" metadata:",optional"`
+}
+
+type PurchaseOrder struct {
+	ID          string     `json:"id"`
+	Buyer       string     `json:"buyer"`
+	Seller      string     `json:"seller"`
+	LineItems   []LineItem `json:"line_items"`
+	AgreedPrice int64      `json:"agreed_price"`
+	Currency    string     `json:"currency"`
+	Status      string     `json:"status"`
+	InvoiceID   string     `json:"invoice_id,omitempty" metadata:",optional"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CreatePurchaseOrder records an order placed by the buyer, who must be the
+// submitter, on the seller. Both are registered participant IDs.
+func (s *SupplyChainContract) CreatePurchaseOrder(ctx contractapi.TransactionContextInterface, id, buyer, seller string, lineItems []LineItem, agreedPrice int64, currency string) error {
+	if buyer == seller {
+		return fmt.Errorf("buyer and seller must be different")
+	}
+	if err := s.requireSubmitterActsFor(ctx, buyer, "buyer"); err != nil {
+		return err
+	}
+	if err := s.requireActiveParticipant(ctx, buyer); err != nil {
+		return err
+	}
+	if err := s.requireActiveParticipant(ctx, seller); err != nil {
+		return err
+	}
+	if len(lineItems) == 0 {
+		return fmt.Errorf("purchase order must have at least one line item")
+	}
+	if agreedPrice < 0 {
+		return fmt.Errorf("agreed price must not be negative, got %d", agreedPrice)
+	}
+
+	seen := make(map[string]bool)
+	for i := range lineItems {
+		item := &lineItems[i]
+		if item.ItemID == "" {
+			return fmt.Errorf("line item %d has no item ID", i)
+		}
+		if seen[item.ItemID] {
+			return fmt.Errorf("duplicate line item ID %s", item.ItemID)
+		}
+		seen[item.ItemID] = true
+		if item.Quantity <= 0 {
+			return fmt.Errorf("quantity for line item %s must be positive, got %d", item.ItemID, item.Quantity)
+		}
+		item.FulfilledBy = nil
+	}
+
+	exists, err := s.PurchaseOrderExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("purchase order with ID %s already exists", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	po := PurchaseOrder{
+		ID:          id,
+		Buyer:       buyer,
+		Seller:      seller,
+		LineItems:   lineItems,
+		AgreedPrice: agreedPrice,
+		Currency:    currency,
+		Status:      "Created",
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
+	}
+
+	err = s.putPurchaseOrder(ctx, &po)
+	if err != nil {
+		return fmt.Errorf("failed to put purchase order into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// AcceptPurchaseOrder is submitted by the seller.
+func (s *SupplyChainContract) AcceptPurchaseOrder(ctx contractapi.TransactionContextInterface, id string) error {
+	return s.setPurchaseOrderStatus(ctx, id, "seller", "Accepted", "Created")
+}
+
+// CancelPurchaseOrder is submitted by the buyer.
+func (s *SupplyChainContract) CancelPurchaseOrder(ctx contractapi.TransactionContextInterface, id string) error {
+	return s.setPurchaseOrderStatus(ctx, id, "buyer", "Cancelled", "Created", "Accepted")
+}
+
+// FulfilPurchaseOrder transfers the given products from the seller, who
+// must be the submitter, to the buyer. productIDs and lineItemIDs are
+// parallel: each product is recorded against the line it satisfies, and
+// every line must be covered exactly.
+func (s *SupplyChainContract) FulfilPurchaseOrder(ctx contractapi.TransactionContextInterface, id string, productIDs []string, lineItemIDs []string) error {
+	if len(productIDs) != len(lineItemIDs) {
+		return fmt.Errorf("got %d product IDs but %d line item IDs", len(productIDs), len(lineItemIDs))
+	}
+
+	po, err := s.QueryPurchaseOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, po.Seller, "seller"); err != nil {
+		return err
+	}
+	if po.Status != "Accepted" {
+		return fmt.Errorf("purchase order %s is %s, only Accepted orders can be fulfilled", id, po.Status)
+	}
+
+	lines := make(map[string]*LineItem)
+	delivered := make(map[string]int64)
+	for i := range po.LineItems {
+		lines[po.LineItems[i].ItemID] = &po.LineItems[i]
+	}
+
+	seen := make(map[string]bool)
+	for i, productID := range productIDs {
+		if seen[productID] {
+			return fmt.Errorf("product %s listed more than once", productID)
+		}
+		seen[productID] = true
+
+		line, ok := lines[lineItemIDs[i]]
+		if !ok {
+			return fmt.Errorf("purchase order %s has no line item %s", id, lineItemIDs[i])
+		}
+
+		product, err := s.readProduct(ctx, productID)
+		if err != nil {
+			return err
+		}
+		if product.Owner != po.Seller {
+			return fmt.Errorf("product %s is owned by %s, not seller %s", productID, product.Owner, po.Seller)
+		}
+		if product.Name != line.Name || product.Unit != line.Unit {
+			return fmt.Errorf("product %s does not match line item %s", productID, line.ItemID)
+		}
+
+		delivered[line.ItemID] += product.Quantity
+		line.FulfilledBy = append(line.FulfilledBy, productID)
+	}
+
+	for _, line := range po.LineItems {
+		if delivered[line.ItemID] != line.Quantity {
+			return fmt.Errorf("line item %s requires %d %s, got %d", line.ItemID, line.Quantity, line.Unit, delivered[line.ItemID])
+		}
+	}
+
+	for _, productID := range productIDs {
+		if err := s.TransferOwnership(ctx, productID, po.Buyer); err != nil {
+			return err
+		}
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	po.Status = "Fulfilled"
+	po.UpdatedAt = txTime
+
+	err = s.putPurchaseOrder(ctx, po)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) QueryPurchaseOrder(ctx contractapi.TransactionContextInterface, id string) (*PurchaseOrder, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(purchaseOrderObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	poJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purchase order from ledger: %v", err)
+	}
+	if poJSON == nil {
+		return nil, fmt.Errorf("the purchase order with ID %s does not exist", id)
+	}
+
+	var po PurchaseOrder
+	err = json.Unmarshal(poJSON, &po)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal purchase order JSON: %v", err)
+	}
+
+	return &po, nil
+}
+
+func (s *SupplyChainContract) PurchaseOrderExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(purchaseOrderObjectType, []string{id})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	poJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return poJSON != nil, nil
+}
+
+// setPurchaseOrderStatus moves an order to status on behalf of one of its
+// parties, "buyer" or "seller".
+func (s *SupplyChainContract) setPurchaseOrderStatus(ctx contractapi.TransactionContextInterface, id, party, status string, allowedFrom ...string) error {
+	po, err := s.QueryPurchaseOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	actor := po.Buyer
+	if party == "seller" {
+		actor = po.Seller
+	}
+	if err := s.requireSubmitterActsFor(ctx, actor, party); err != nil {
+		return err
+	}
+
+	allowed := false
+	for _, from := range allowedFrom {
+		if po.Status == from {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("purchase order %s is %s and cannot be moved to %s", id, po.Status, status)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	po.Status = status
+	po.UpdatedAt = txTime
+
+	err = s.putPurchaseOrder(ctx, po)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase order: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) putPurchaseOrder(ctx contractapi.TransactionContextInterface, po *PurchaseOrder) error {
+	key, err := ctx.GetStub().CreateCompositeKey(purchaseOrderObjectType, []string{po.ID})
+	if err != nil {
+		return err
+	}
+
+	poJSON, err := marshalState(po)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, poJSON)
+}
@@ -0,0 +1,114 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+// smartphoneOrder orders CompanyB's p2 for CompanyA.
+const smartphoneOrder = `[{"item_id":"l1","name":"Smartphone","quantity":1,"unit":"each","unit_price":500}]`
+
+func TestCreatePurchaseOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		buyer     string
+		seller    string
+		lineItems string
+		wantErr   string
+	}{
+		{"buyer creates", org1, "CompanyA", "CompanyB", smartphoneOrder, ""},
+		{"seller creates for buyer", org2, "CompanyA", "CompanyB", smartphoneOrder, "not authorized to act as buyer CompanyA"},
+		{"unregistered seller", org1, "CompanyA", "Nobody", smartphoneOrder, "Nobody is not a registered participant"},
+		{"same parties", org1, "CompanyA", "CompanyA", smartphoneOrder, "must be different"},
+		{"no line items", org1, "CompanyA", "CompanyB", `[]`, "at least one line item"},
+		{"duplicate line", org1, "CompanyA", "CompanyB", `[{"item_id":"l1","quantity":1},{"item_id":"l1","quantity":1}]`, "duplicate line item ID l1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "CreatePurchaseOrder", "po1", tt.buyer, tt.seller, tt.lineItems, "500", "EUR")
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPurchaseOrderLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []txStep
+		wantStatus string
+		wantOwner  string
+	}{
+		{
+			name: "accepted and fulfilled by seller",
+			steps: []txStep{
+				{org2, "AcceptPurchaseOrder", nil, ""},
+				{org2, "FulfilPurchaseOrder", []string{`["p2"]`, `["l1"]`}, ""},
+			},
+			wantStatus: "Fulfilled",
+			wantOwner:  "CompanyA",
+		},
+		{
+			name: "buyer cannot accept",
+			steps: []txStep{
+				{org1, "AcceptPurchaseOrder", nil, "not authorized to act as seller CompanyB"},
+			},
+			wantStatus: "Created",
+			wantOwner:  "CompanyB",
+		},
+		{
+			name: "buyer cannot fulfil",
+			steps: []txStep{
+				{org2, "AcceptPurchaseOrder", nil, ""},
+				{org1, "FulfilPurchaseOrder", []string{`["p2"]`, `["l1"]`}, "not authorized to act as seller CompanyB"},
+			},
+			wantStatus: "Accepted",
+			wantOwner:  "CompanyB",
+		},
+		{
+			name: "fulfil before accept",
+			steps: []txStep{
+				{org2, "FulfilPurchaseOrder", []string{`["p2"]`, `["l1"]`}, "only Accepted orders can be fulfilled"},
+			},
+			wantStatus: "Created",
+			wantOwner:  "CompanyB",
+		},
+		{
+			name: "buyer cancels",
+			steps: []txStep{
+				{org1, "CancelPurchaseOrder", nil, ""},
+			},
+			wantStatus: "Cancelled",
+			wantOwner:  "CompanyB",
+		},
+		{
+			name: "seller cannot cancel",
+			steps: []txStep{
+				{org2, "CancelPurchaseOrder", nil, "not authorized to act as buyer CompanyA"},
+			},
+			wantStatus: "Created",
+			wantOwner:  "CompanyB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org1, "CreatePurchaseOrder", "po1", "CompanyA", "CompanyB", smartphoneOrder, "500", "EUR")
+
+			l.run(tt.steps, "po1")
+
+			var po PurchaseOrder
+			l.evaluate(org1, &po, "QueryPurchaseOrder", "po1")
+			if po.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", po.Status, tt.wantStatus)
+			}
+			if owner := l.product("p2").Owner; owner != tt.wantOwner {
+				t.Errorf("p2 owner = %s, want %s", owner, tt.wantOwner)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const returnObjectType = "ReturnAuthorization"
+
+type ReturnAuthorization struct {
+	ID           string    `json:"id"`
+	ProductID    string    `json:"product_id"`
+	Holder       string    `json:"holder"`
+	Seller       string    `json:"seller"`
+	Reason       string    `json:"reason"`
+	Status       string    `json:"status"`
+	DenialReason string    `json:"denial_reason,omitempty" metadata:",optional"`
+	Disposition  string    `json:"disposition,omitempty" metadata:",optional"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RequestReturn opens a return for a product on behalf of its current holder,
+// who submits it. The seller is the owner the product was most recently
+// transferred from; it submits every later step of the return.
+func (s *SupplyChainContract) RequestReturn(ctx contractapi.TransactionContextInterface, id, productID, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("a reason is required to request a return")
+	}
+
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "holder"); err != nil {
+		return err
+	}
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+
+	seller, err := s.previousOwner(ctx, product)
+	if err != nil {
+		return err
+	}
+	if seller == "" {
+		return fmt.Errorf("product %s has never been transferred and cannot be returned", productID)
+	}
+
+	existing, err := s.GetReturnsByProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	for _, rma := range existing {
+		if rma.Status == "Requested" || rma.Status == "Approved" || rma.Status == "Returned" || rma.Status == "Inspected" {
+			return fmt.Errorf("product %s already has open return %s", productID, rma.ID)
+		}
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(returnObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	rmaJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if rmaJSON != nil {
+		return fmt.Errorf("return with ID %s already exists", id)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	rma := ReturnAuthorization{
+		ID:        id,
+		ProductID: productID,
+		Holder:    product.Owner,
+		Seller:    seller,
+		Reason:    reason,
+		Status:    "Requested",
+		CreatedAt: txTime,
+		UpdatedAt: txTime,
+	}
+
+	err = s.putReturn(ctx, &rma)
+	if err != nil {
+		return fmt.Errorf("failed to put return into ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) ApproveReturn(ctx contractapi.TransactionContextInterface, id string) error {
+	rma, err := s.sellerReturn(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.advanceReturn(ctx, rma, "Requested", "Approved", "")
+}
+
+func (s *SupplyChainContract) DenyReturn(ctx contractapi.TransactionContextInterface, id, reason string) error {
+	rma, err := s.sellerReturn(ctx, id)
+	if err != nil {
+		return err
+	}
+	rma.DenialReason = reason
+	return s.advanceReturn(ctx, rma, "Requested", "Denied", "")
+}
+
+// ReceiveReturn records that the seller has physically received the product.
+func (s *SupplyChainContract) ReceiveReturn(ctx contractapi.TransactionContextInterface, id string) error {
+	rma, err := s.sellerReturn(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.advanceReturn(ctx, rma, "Approved", "Returned", "Returned")
+}
+
+func (s *SupplyChainContract) InspectReturn(ctx contractapi.TransactionContextInterface, id string) error {
+	rma, err := s.sellerReturn(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.advanceReturn(ctx, rma, "Returned", "Inspected", "Inspected")
+}
+
+// CompleteReturn closes an inspected return with a disposition of Refurbished
+// or Scrapped and reverts ownership of the product to the seller, which must
+// still be active. The reversal is subject to any TransferOwnership approval
+// policy on the product.
+func (s *SupplyChainContract) CompleteReturn(ctx contractapi.TransactionContextInterface, id, disposition string) error {
+	if disposition != "Refurbished" && disposition != "Scrapped" {
+		return fmt.Errorf("disposition must be Refurbished or Scrapped, got %q", disposition)
+	}
+
+	rma, err := s.sellerReturn(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rma.Status != "Inspected" {
+		return fmt.Errorf("return %s is %s, only Inspected returns can be completed", id, rma.Status)
+	}
+	if err := s.requireNoApprovalPolicy(ctx, "TransferOwnership", rma.ProductID); err != nil {
+		return err
+	}
+
+	rma.Disposition = disposition
+	return s.advanceReturn(ctx, rma, "Inspected", "Completed", disposition)
+}
+
+func (s *SupplyChainContract) QueryReturn(ctx contractapi.TransactionContextInterface, id string) (*ReturnAuthorization, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(returnObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	rmaJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read return from ledger: %v", err)
+	}
+	if rmaJSON == nil {
+		return nil, fmt.Errorf("the return with ID %s does not exist", id)
+	}
+
+	var rma ReturnAuthorization
+	err = json.Unmarshal(rmaJSON, &rma)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal return JSON: %v", err)
+	}
+
+	return &rma, nil
+}
+
+func (s *SupplyChainContract) GetReturnsByProduct(ctx contractapi.TransactionContextInterface, productID string) ([]*ReturnAuthorization, error) {
+	return s.queryReturns(ctx, func(rma *ReturnAuthorization) bool {
+		return rma.ProductID == productID
+	})
+}
+
+// GetReturnsByOrganization lists returns where org is either the holder or
+// the seller.
+func (s *SupplyChainContract) GetReturnsByOrganization(ctx contractapi.TransactionContextInterface, org string) ([]*ReturnAuthorization, error) {
+	return s.queryReturns(ctx, func(rma *ReturnAuthorization) bool {
+		return rma.Holder == org || rma.Seller == org
+	})
+}
+
+func (s *SupplyChainContract) queryReturns(ctx contractapi.TransactionContextInterface, match func(*ReturnAuthorization) bool) ([]*ReturnAuthorization, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(returnObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var returns []*ReturnAuthorization
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var rma ReturnAuthorization
+		if err := json.Unmarshal(queryResponse.Value, &rma); err != nil {
+			return nil, err
+		}
+		if match(&rma) {
+			returns = append(returns, &rma)
+		}
+	}
+
+	return returns, nil
+}
+
+// sellerReturn reads return id for a transaction that only its seller may
+// submit.
+func (s *SupplyChainContract) sellerReturn(ctx contractapi.TransactionContextInterface, id string) (*ReturnAuthorization, error) {
+	rma, err := s.QueryReturn(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireSubmitterActsFor(ctx, rma.Seller, "seller"); err != nil {
+		return nil, err
+	}
+	return rma, nil
+}
+
+// advanceReturn moves rma from one status to the next and, when
+// productStatus is set, applies it to the returned product.
+func (s *SupplyChainContract) advanceReturn(ctx contractapi.TransactionContextInterface, rma *ReturnAuthorization, from, to, productStatus string) error {
+	if rma.Status != from {
+		return fmt.Errorf("return %s is %s and cannot be moved to %s", rma.ID, rma.Status, to)
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if productStatus != "" {
+		product, err := s.readProduct(ctx, rma.ProductID)
+		if err != nil {
+			return err
+		}
+		product.Status = productStatus
+		product.UpdatedAt = txTime
+		if to == "Completed" {
+			if err := s.transferProduct(ctx, product, rma.Seller); err != nil {
+				return err
+			}
+		} else if err := s.putProduct(ctx, product); err != nil {
+			return fmt.Errorf("failed to update product: %v", err)
+		}
+	}
+
+	rma.Status = to
+	rma.UpdatedAt = txTime
+
+	err = s.putReturn(ctx, rma)
+	if err != nil {
+		return fmt.Errorf("failed to update return: %v", err)
+	}
+
+	return nil
+}
+
+// previousOwner returns the owner the product was most recently transferred
+// from, or "" if ownership never changed. It reads the transfer record
+// written by transferProduct rather than the key history, which is not
+// validated against concurrent writes in a submitted transaction.
+func (s *SupplyChainContract) previousOwner(ctx contractapi.TransactionContextInterface, product *Product) (string, error) {
+	transfer, err := s.queryTransfer(ctx, product.ID)
+	if err != nil {
+		return "", err
+	}
+	if transfer == nil {
+		return "", nil
+	}
+	return transfer.From, nil
+}
+
+func (s *SupplyChainContract) putReturn(ctx contractapi.TransactionContextInterface, rma *ReturnAuthorization) error {
+	key, err := ctx.GetStub().CreateCompositeKey(returnObjectType, []string{rma.ID})
+	if err != nil {
+		return err
+	}
+
+	rmaJSON, err := marshalState(rma)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, rmaJSON)
+}
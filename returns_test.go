@@ -0,0 +1,112 @@
+package smartcontract
+
+import (
+	"testing"
+)
+
+func TestRequestReturn(t *testing.T) {
+	l := newSoldLedger(t)
+
+	l.run([]txStep{
+		{org1, "RequestReturn", []string{"r1", "p1", "faulty"}, "has never been transferred"},
+		{org2, "RequestReturn", []string{"r1", "p2", "faulty"}, "not authorized to act as holder CompanyA"},
+		{org1, "RequestReturn", []string{"r1", "p2", ""}, "a reason is required"},
+		{org1, "RequestReturn", []string{"r1", "p2", "faulty"}, ""},
+		{org1, "RequestReturn", []string{"r2", "p2", "faulty"}, "already has open return r1"},
+	})
+
+	var rma ReturnAuthorization
+	l.evaluate(org1, &rma, "QueryReturn", "r1")
+	if rma.Holder != "CompanyA" || rma.Seller != "CompanyB" || rma.Status != "Requested" {
+		t.Errorf("return = %+v, want CompanyA returning to CompanyB, Requested", rma)
+	}
+}
+
+func TestReturnLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []txStep
+		wantStatus string
+		wantOwner  string
+	}{
+		{
+			name: "seller completes",
+			steps: []txStep{
+				{org2, "ApproveReturn", nil, ""},
+				{org2, "ReceiveReturn", nil, ""},
+				{org2, "InspectReturn", nil, ""},
+				{org2, "CompleteReturn", []string{"Refurbished"}, ""},
+			},
+			wantStatus: "Completed",
+			wantOwner:  "CompanyB",
+		},
+		{
+			name:       "holder cannot approve",
+			steps:      []txStep{{org1, "ApproveReturn", nil, "not authorized to act as seller CompanyB"}},
+			wantStatus: "Requested",
+			wantOwner:  "CompanyA",
+		},
+		{
+			name:       "holder cannot deny",
+			steps:      []txStep{{org1, "DenyReturn", []string{"no"}, "not authorized to act as seller CompanyB"}},
+			wantStatus: "Requested",
+			wantOwner:  "CompanyA",
+		},
+		{
+			name:       "seller denies",
+			steps:      []txStep{{org2, "DenyReturn", []string{"worn"}, ""}},
+			wantStatus: "Denied",
+			wantOwner:  "CompanyA",
+		},
+		{
+			name: "holder cannot complete",
+			steps: []txStep{
+				{org2, "ApproveReturn", nil, ""},
+				{org2, "ReceiveReturn", nil, ""},
+				{org2, "InspectReturn", nil, ""},
+				{org1, "CompleteReturn", []string{"Scrapped"}, "not authorized to act as seller CompanyB"},
+			},
+			wantStatus: "Inspected",
+			wantOwner:  "CompanyA",
+		},
+		{
+			name:       "complete before inspection",
+			steps:      []txStep{{org2, "CompleteReturn", []string{"Scrapped"}, "only Inspected returns can be completed"}},
+			wantStatus: "Requested",
+			wantOwner:  "CompanyA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newSoldLedger(t)
+			l.submit(org1, "RequestReturn", "r1", "p2", "faulty")
+
+			l.run(tt.steps, "r1")
+
+			var rma ReturnAuthorization
+			l.evaluate(org1, &rma, "QueryReturn", "r1")
+			if rma.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", rma.Status, tt.wantStatus)
+			}
+			if owner := l.product("p2").Owner; owner != tt.wantOwner {
+				t.Errorf("p2 owner = %s, want %s", owner, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestCompleteReturnRequiresActiveSeller(t *testing.T) {
+	l := newSoldLedger(t)
+	l.submit(org1, "RequestReturn", "r1", "p2", "faulty")
+	l.submit(org2, "ApproveReturn", "r1")
+	l.submit(org2, "ReceiveReturn", "r1")
+	l.submit(org2, "InspectReturn", "r1")
+	l.submit(admin, "SuspendParticipant", "CompanyB")
+
+	_, err := l.ledger.Submit(org2, "CompleteReturn", "r1", "Refurbished")
+	expectError(t, err, "participant CompanyB is Suspended")
+	if owner := l.product("p2").Owner; owner != "CompanyA" {
+		t.Errorf("p2 owner = %s, want CompanyA", owner)
+	}
+}
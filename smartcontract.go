@@ -1,49 +1,154 @@
-package main
+// Package smartcontract implements the supply chain contract. It is a
+// library so that the REST gateway and operator CLI can run it in process;
+// the chaincode binary deployed to peers is built from cmd/chaincode.
+package smartcontract
 
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
-	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
 )
 
+const transferObjectType = "Transfer"
+
 type Product struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Status      string `json:"status"`
-	Owner       string `json:"owner"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
-	Category    string `json:"category"`
-	Description string `json:"description"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Status      string            `json:"status"`
+	Owner       string            `json:"owner"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Category    string            `json:"category"`
+	Description string            `json:"description"`
+	Quantity    int64             `json:"quantity"`
+	Unit        string            `json:"unit"`
+	Parents     []string          `json:"parents,omitempty" metadata:",optional"`
+	Children    []string          `json:"children,omitempty" metadata:",optional"`
+	Attributes  map[string]string `json:"attributes,omitempty" metadata:",optional"`
+	GTIN        string            `json:"gtin,omitempty" metadata:",optional"`
+	Serial      string            `json:"serial,omitempty" metadata:",optional"`
+	EPC         string            `json:"epc,omitempty" metadata:",optional"`
+	Location    string            `json:"location,omitempty" metadata:",optional"`
+	Footprint   map[string]int64  `json:"footprint,omitempty" metadata:",optional"`
+
+	SchemaVersion int `json:"schema_version"`
+}
+
+// ProductTransfer records the most recent change of a product's owner, for
+// the transactions that need to know who the product came from. InvoiceID is
+// set once the transfer has been billed, so it is invoiced at most once.
+type ProductTransfer struct {
+	ProductID     string    `json:"product_id"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	TxID          string    `json:"tx_id"`
+	TransferredAt time.Time `json:"transferred_at"`
+	InvoiceID     string    `json:"invoice_id,omitempty" metadata:",optional"`
 }
 
 type SupplyChainContract struct {
 	contractapi.Contract
+
+	// LogArguments makes every invocation log its arguments as well as its
+	// name and submitter. It is off by default because arguments can carry
+	// commercially sensitive data.
+	LogArguments bool
+}
+
+// GetEvaluateTransactions lists the read-only transactions. They are marked
+// as evaluate-only in the contract metadata and stay available while the
+// contract is paused.
+func (s *SupplyChainContract) GetEvaluateTransactions() []string {
+	return []string{
+		"QueryProduct", "ProductExists", "GetAllProducts", "GetProductsWithPagination",
+		"QueryPurchaseOrder", "PurchaseOrderExists",
+		"QueryInvoice", "GetOutstandingReceivables",
+		"GetBalance", "QueryEscrow",
+		"QueryReturn", "GetReturnsByProduct", "GetReturnsByOrganization",
+		"QueryWarranty", "QueryWarrantyClaim", "GetWarrantyClaims",
+		"QueryPendingOperation", "GetProductEndorsementPolicy",
+		"QueryParticipant", "ParticipantExists", "GetAllParticipants",
+		"GetConfig", "GetConfigHistory",
+		"QueryCategory", "CategoryExists", "GetAllCategories",
+		"GetActiveHolds", "GetPauseState",
+		"GetAuditChains", "GetAuditLog", "GetAuditLogByTimeRange", "VerifyAuditChain",
+		"GetProductByEPC", "ValidateGS1Identifier", "GetProductEPCIS",
+		"QueryLocation", "LocationExists", "GetProductsAtLocation", "GetProductLocationTrail",
+		"GetProductFootprint",
+		"GetProductHistory", "GetProductsByTimeRange",
+		"GetMigrationProgress", "VerifyStateEncoding",
+	}
 }
 
-func (s *SupplyChainContract) getTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+// getTxTime returns the transaction timestamp, which every stored timestamp is
+// taken from, to the nanosecond. Records written before timestamps were typed
+// hold whole-second RFC3339 strings, which time.Time decodes unchanged.
+func (s *SupplyChainContract) getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	if sc, ok := ctx.(*SupplyChainContext); ok && !sc.txTime.IsZero() {
+		return sc.txTime, nil
+	}
+
 	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
 	}
-	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).Format(time.RFC3339), nil
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(), nil
 }
 
+// InitLedger seeds the demonstration participants and products. It is an
+// admin transaction and fails if any of them already exist, so it cannot be
+// replayed to reset their state.
 func (s *SupplyChainContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
-	timestamp, err := s.getTimestamp(ctx)
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
 	if err != nil {
 		return err
 	}
+	participants := []Participant{
+		{ID: "CompanyA", LegalName: "Company A", MSPID: "Org1MSP", Role: "Manufacturer", Status: "Active", CreatedAt: txTime, UpdatedAt: txTime},
+		{ID: "CompanyB", LegalName: "Company B", MSPID: "Org2MSP", Role: "Manufacturer", Status: "Active", CreatedAt: txTime, UpdatedAt: txTime},
+	}
+
+	mspIDs := make(map[string]string)
+	for _, participant := range participants {
+		exists, err := s.ParticipantExists(ctx, participant.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("ledger is already initialized: participant %s already exists", participant.ID)
+		}
+		if err := s.putParticipant(ctx, &participant); err != nil {
+			return err
+		}
+		mspIDs[participant.ID] = participant.MSPID
+	}
 
 	products := []Product{
-		{ID: "p1", Name: "Laptop", Status: "Manufactured", Owner: "CompanyA", CreatedAt: timestamp, UpdatedAt: timestamp, Description: "High-end gaming laptop", Category: "Electronics"},
-		{ID: "p2", Name: "Smartphone", Status: "Manufactured", Owner: "CompanyB", CreatedAt: timestamp, UpdatedAt: timestamp, Description: "Latest model smartphone", Category: "Electronics"},
+		{ID: "p1", Name: "Laptop", Status: "Manufactured", Owner: "CompanyA", CreatedAt: txTime, UpdatedAt: txTime, Description: "High-end gaming laptop", Category: "Electronics", Quantity: 1, Unit: "each"},
+		{ID: "p2", Name: "Smartphone", Status: "Manufactured", Owner: "CompanyB", CreatedAt: txTime, UpdatedAt: txTime, Description: "Latest model smartphone", Category: "Electronics", Quantity: 1, Unit: "each"},
 	}
 
+	// Participants written above are not yet readable in this transaction,
+	// so the owners' MSP IDs are supplied directly.
 	for _, product := range products {
-		if err := s.putProduct(ctx, &product); err != nil {
+		exists, err := s.ProductExists(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("ledger is already initialized: product %s already exists", product.ID)
+		}
+		if err := s.requireNotFrozen(ctx, &product); err != nil {
+			return err
+		}
+		if err := s.writeProduct(ctx, &product, mspIDs[product.Owner]); err != nil {
 			return err
 		}
 	}
@@ -52,6 +157,20 @@ func (s *SupplyChainContract) InitLedger(ctx contractapi.TransactionContextInter
 }
 
 func (s *SupplyChainContract) CreateProduct(ctx contractapi.TransactionContextInterface, id, name, owner, description, category string) error {
+	return s.CreateProductWithAttributes(ctx, id, name, owner, description, category, nil)
+}
+
+func (s *SupplyChainContract) CreateProductWithAttributes(ctx contractapi.TransactionContextInterface, id, name, owner, description, category string, attributes map[string]string) error {
+	if err := s.requireActiveParticipant(ctx, owner); err != nil {
+		return err
+	}
+	if err := s.validateCategory(ctx, category); err != nil {
+		return err
+	}
+	if err := s.validateAttributes(ctx, category, attributes); err != nil {
+		return err
+	}
+
 	exists, err := s.ProductExists(ctx, id)
 	if err != nil {
 		return err
@@ -60,7 +179,7 @@ func (s *SupplyChainContract) CreateProduct(ctx contractapi.TransactionContextIn
 		return fmt.Errorf("product with ID %s already exists", id)
 	}
 
-	timestamp, err := s.getTimestamp(ctx)
+	txTime, err := s.getTxTime(ctx)
 	if err != nil {
 		return err
 	}
@@ -70,10 +189,13 @@ func (s *SupplyChainContract) CreateProduct(ctx contractapi.TransactionContextIn
 		Name:        name,
 		Status:      "Manufactured",
 		Owner:       owner,
-		CreatedAt:   timestamp,
-		UpdatedAt:   timestamp,
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
 		Description: description,
 		Category:    category,
+		Quantity:    1,
+		Unit:        "each",
+		Attributes:  attributes,
 	}
 
 	err = s.putProduct(ctx, &newProduct)
@@ -84,34 +206,52 @@ func (s *SupplyChainContract) CreateProduct(ctx contractapi.TransactionContextIn
 	return nil
 }
 
+// UpdateProduct rewrites a product's status, owner, description and
+// category. A change of owner or a recall is subject to the same approval
+// policies as TransferOwnership and a proposed Recall.
 func (s *SupplyChainContract) UpdateProduct(ctx contractapi.TransactionContextInterface, id string, newStatus string, newOwner string, newDescription string, newCategory string) error {
-	exists, err := s.ProductExists(ctx, id)
+	txTime, err := s.getTxTime(ctx)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("product with ID %s does not exist", id)
-	}
 
-	timestamp, err := s.getTimestamp(ctx)
+	existingProduct, err := s.readProduct(ctx, id)
 	if err != nil {
 		return err
 	}
-
-	existingProduct, err := s.QueryProduct(ctx, id)
-	if err != nil {
+	if err := s.requireSubmitterActsFor(ctx, existingProduct.Owner, "owner"); err != nil {
 		return err
 	}
 
-	if existingProduct.Owner != newOwner {
-		existingProduct.Owner = newOwner
-		existingProduct.UpdatedAt = timestamp
+	if newStatus == "Recalled" && existingProduct.Status != "Recalled" {
+		if err := s.requireNoApprovalPolicy(ctx, "Recall", id); err != nil {
+			return err
+		}
+	}
+	ownerChanged := existingProduct.Owner != newOwner
+	if ownerChanged {
+		if err := s.requireNoApprovalPolicy(ctx, "TransferOwnership", id); err != nil {
+			return err
+		}
+	}
+
+	if existingProduct.Category != newCategory {
+		if err := s.validateCategory(ctx, newCategory); err != nil {
+			return err
+		}
+		if err := s.validateAttributes(ctx, newCategory, existingProduct.Attributes); err != nil {
+			return err
+		}
 	}
 
 	existingProduct.Status = newStatus
 	existingProduct.Description = newDescription
 	existingProduct.Category = newCategory
-	existingProduct.UpdatedAt = timestamp
+	existingProduct.UpdatedAt = txTime
+
+	if ownerChanged {
+		return s.transferProduct(ctx, existingProduct, newOwner)
+	}
 
 	err = s.putProduct(ctx, existingProduct)
 	if err != nil {
@@ -122,44 +262,120 @@ func (s *SupplyChainContract) UpdateProduct(ctx contractapi.TransactionContextIn
 }
 
 func (s *SupplyChainContract) TransferOwnership(ctx contractapi.TransactionContextInterface, id, newOwner string) error {
-	exists, err := s.ProductExists(ctx, id)
+	if err := s.requireNoApprovalPolicy(ctx, "TransferOwnership", id); err != nil {
+		return err
+	}
+
+	product, err := s.readProduct(ctx, id)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("product with ID %s does not exist", id)
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "owner"); err != nil {
+		return err
 	}
+	return s.transferProduct(ctx, product, newOwner)
+}
 
-	timestamp, err := s.getTimestamp(ctx)
+func (s *SupplyChainContract) transferOwnership(ctx contractapi.TransactionContextInterface, id, newOwner string) error {
+	existingProduct, err := s.readProduct(ctx, id)
 	if err != nil {
 		return err
 	}
+	return s.transferProduct(ctx, existingProduct, newOwner)
+}
+
+// transferProduct writes product, along with any other changes the caller
+// has made to it, as owned by newOwner, and starts its warranty. It is the
+// only path by which a product changes hands.
+func (s *SupplyChainContract) transferProduct(ctx contractapi.TransactionContextInterface, product *Product, newOwner string) error {
+	if err := s.requireActiveParticipant(ctx, newOwner); err != nil {
+		return err
+	}
 
-	existingProduct, err := s.QueryProduct(ctx, id)
+	txTime, err := s.getTxTime(ctx)
 	if err != nil {
 		return err
 	}
 
-	existingProduct.Owner = newOwner
-	existingProduct.UpdatedAt = timestamp
+	previousOwner := product.Owner
+	product.Owner = newOwner
+	product.UpdatedAt = txTime
 
-	err = s.putProduct(ctx, existingProduct)
+	err = s.putProduct(ctx, product)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %v", err)
 	}
 
-	return nil
+	if previousOwner != newOwner {
+		transfer := ProductTransfer{
+			ProductID:     product.ID,
+			From:          previousOwner,
+			To:            newOwner,
+			TxID:          ctx.GetStub().GetTxID(),
+			TransferredAt: txTime,
+		}
+		if err := s.putTransfer(ctx, &transfer); err != nil {
+			return fmt.Errorf("failed to record transfer: %v", err)
+		}
+	}
+
+	return s.startWarranty(ctx, product, previousOwner)
+}
+
+// queryTransfer returns the most recent transfer of a product, or nil if it
+// has never changed hands.
+func (s *SupplyChainContract) queryTransfer(ctx contractapi.TransactionContextInterface, productID string) (*ProductTransfer, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(transferObjectType, []string{productID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	transferJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer from ledger: %v", err)
+	}
+	if transferJSON == nil {
+		return nil, nil
+	}
+
+	var transfer ProductTransfer
+	err = json.Unmarshal(transferJSON, &transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transfer JSON: %v", err)
+	}
+
+	return &transfer, nil
+}
+
+func (s *SupplyChainContract) putTransfer(ctx contractapi.TransactionContextInterface, transfer *ProductTransfer) error {
+	key, err := ctx.GetStub().CreateCompositeKey(transferObjectType, []string{transfer.ProductID})
+	if err != nil {
+		return err
+	}
+
+	transferJSON, err := marshalState(transfer)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, transferJSON)
 }
 
+// QueryProduct returns the product as projected for the submitter; see
+// projectProduct.
 func (s *SupplyChainContract) QueryProduct(ctx contractapi.TransactionContextInterface, id string) (*Product, error) {
-	exists, err := s.ProductExists(ctx, id)
+	product, err := s.readProduct(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if !exists {
-		return nil, fmt.Errorf("the product with ID %s does not exist", id)
+
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return viewer.project(product), nil
+}
 
+func (s *SupplyChainContract) readProduct(ctx contractapi.TransactionContextInterface, id string) (*Product, error) {
 	productJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read product from ledger: %v", err)
@@ -168,21 +384,85 @@ func (s *SupplyChainContract) QueryProduct(ctx contractapi.TransactionContextInt
 		return nil, fmt.Errorf("the product with ID %s does not exist", id)
 	}
 
-	var product Product
-	err = json.Unmarshal(productJSON, &product)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal product JSON: %v", err)
+	return unmarshalProduct(productJSON)
+}
+
+// putProduct is the single write path for products: it rejects frozen
+// products and pins the key-level endorsement policy to the current owner,
+// so every mutator gets both.
+func (s *SupplyChainContract) putProduct(ctx contractapi.TransactionContextInterface, product *Product) error {
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
 	}
 
-	return &product, nil
+	mspID, err := s.ownerMSPID(ctx, product.Owner)
+	if err != nil {
+		return err
+	}
+	return s.writeProduct(ctx, product, mspID)
 }
 
-func (s *SupplyChainContract) putProduct(ctx contractapi.TransactionContextInterface, product *Product) error {
-	productJSON, err := json.Marshal(product)
+func (s *SupplyChainContract) writeProduct(ctx contractapi.TransactionContextInterface, product *Product, ownerMSPID string) error {
+	product.SchemaVersion = productSchemaVersion
+	productJSON, err := marshalState(product)
 	if err != nil {
 		return err
 	}
-	return ctx.GetStub().PutState(product.ID, productJSON)
+	if err := ctx.GetStub().PutState(product.ID, productJSON); err != nil {
+		return err
+	}
+	return s.setProductEndorsement(ctx, product.ID, ownerMSPID)
+}
+
+// deleteProduct removes a product together with its transfer record and the
+// index entries and authenticity token that refer to it.
+func (s *SupplyChainContract) deleteProduct(ctx contractapi.TransactionContextInterface, product *Product) error {
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+
+	transferKey, err := ctx.GetStub().CreateCompositeKey(transferObjectType, []string{product.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	keys := []string{transferKey}
+	if product.EPC != "" {
+		key, err := ctx.GetStub().CreateCompositeKey(epcIndexObjectType, []string{product.EPC})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if product.Location != "" {
+		key, err := ctx.GetStub().CreateCompositeKey(productLocationObjectType, []string{product.Location, product.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	tokenKey, err := ctx.GetStub().CreateCompositeKey(authTokenProductObjectType, []string{product.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	codeHash, err := ctx.GetStub().GetState(tokenKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if codeHash != nil {
+		key, err := ctx.GetStub().CreateCompositeKey(authTokenObjectType, []string{string(codeHash)})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		keys = append(keys, tokenKey, key)
+	}
+
+	for _, key := range append(keys, product.ID) {
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("failed to delete product %s: %v", product.ID, err)
+		}
+	}
+	return nil
 }
 
 func (s *SupplyChainContract) ProductExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
@@ -194,6 +474,11 @@ func (s *SupplyChainContract) ProductExists(ctx contractapi.TransactionContextIn
 }
 
 func (s *SupplyChainContract) GetAllProducts(ctx contractapi.TransactionContextInterface) ([]*Product, error) {
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
 	if err != nil {
 		return nil, err
@@ -207,26 +492,137 @@ func (s *SupplyChainContract) GetAllProducts(ctx contractapi.TransactionContextI
 			return nil, err
 		}
 
-		var product Product
-		if err := json.Unmarshal(queryResponse.Value, &product); err != nil {
+		product, err := unmarshalProduct(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, viewer.project(product))
+	}
+
+	return products, nil
+}
+
+// GetProductsByTimeRange returns the products whose created_at or updated_at
+// falls in [from, to), sorted on that field. Either bound may be empty to
+// leave that end open. Bounds are RFC3339 with optional fractional seconds.
+func (s *SupplyChainContract) GetProductsByTimeRange(ctx contractapi.TransactionContextInterface, field, from, to string, descending bool) ([]*Product, error) {
+	var timeOf func(*Product) time.Time
+	switch field {
+	case "created_at":
+		timeOf = func(p *Product) time.Time { return p.CreatedAt }
+	case "updated_at":
+		timeOf = func(p *Product) time.Time { return p.UpdatedAt }
+	default:
+		return nil, fmt.Errorf("field must be created_at or updated_at, got %q", field)
+	}
+
+	var fromTime, toTime time.Time
+	var err error
+	if from != "" {
+		if fromTime, err = time.Parse(time.RFC3339Nano, from); err != nil {
+			return nil, fmt.Errorf("from must be RFC3339: %v", err)
+		}
+	}
+	if to != "" {
+		if toTime, err = time.Parse(time.RFC3339Nano, to); err != nil {
+			return nil, fmt.Errorf("to must be RFC3339: %v", err)
+		}
+	}
+
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	products := []*Product{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		product, err := unmarshalProduct(queryResponse.Value)
+		if err != nil {
 			return nil, err
 		}
-		products = append(products, &product)
+		at := timeOf(product)
+		if (from != "" && at.Before(fromTime)) || (to != "" && !at.Before(toTime)) {
+			continue
+		}
+		products = append(products, product)
 	}
 
+	// Ties are broken by ID so that every peer returns the same order.
+	sort.Slice(products, func(i, j int) bool {
+		ti, tj := timeOf(products[i]), timeOf(products[j])
+		if ti.Equal(tj) {
+			return products[i].ID < products[j].ID
+		}
+		return ti.Before(tj) != descending
+	})
+
+	for i, product := range products {
+		products[i] = viewer.project(product)
+	}
 	return products, nil
 }
 
-func main() {
-	chaincode, err := contractapi.NewChaincode(&SupplyChainContract{})
+type PaginatedProducts struct {
+	Records             []*Product `json:"records"`
+	FetchedRecordsCount int32      `json:"fetched_records_count"`
+	Bookmark            string     `json:"bookmark"`
+}
+
+func (s *SupplyChainContract) GetProductsWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*PaginatedProducts, error) {
+	pageSize, err := s.pageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", pageSize, bookmark)
 	if err != nil {
-		fmt.Printf("Error creating supply chain chaincode: %s", err.Error())
-		return
+		return nil, err
 	}
+	defer resultsIterator.Close()
+
+	products := []*Product{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
 
-	if err := chaincode.Start(); err != nil {
-		fmt.Printf("Error starting supply chain chaincode: %s", err.Error())
+		product, err := unmarshalProduct(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, viewer.project(product))
 	}
+
+	return &PaginatedProducts{
+		Records:             products,
+		FetchedRecordsCount: metadata.FetchedRecordsCount,
+		Bookmark:            metadata.Bookmark,
+	}, nil
 }
 
+// NewContract returns the contract with its transaction context and hooks
+// wired up, ready to pass to contractapi.NewChaincode.
+func NewContract() *SupplyChainContract {
+	contract := &SupplyChainContract{}
+	contract.TransactionContextHandler = new(SupplyChainContext)
+	contract.BeforeTransaction = contract.beforeTransaction
+	contract.AfterTransaction = contract.afterTransaction
+	contract.UnknownTransaction = contract.unknownTransaction
+	return contract
 }
@@ -0,0 +1,73 @@
+package smartcontract
+
+import (
+	"testing"
+)
+
+func TestTransferOwnership(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID string
+		wantErr   string
+		wantOwner string
+	}{
+		{"seeded product", "p1", "", "CompanyB"},
+		{"unknown product", "p9", "does not exist", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+
+			_, err := l.ledger.Submit(org1, "TransferOwnership", tt.productID, "CompanyB")
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			if owner := l.product(tt.productID).Owner; owner != tt.wantOwner {
+				t.Errorf("%s owner = %s, want %s", tt.productID, owner, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestInitLedger(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []txStep
+		wantErr string
+	}{
+		{"admin", nil, ""},
+		{"not admin", []txStep{{org1, "InitLedger", nil, "admin role required"}}, ""},
+		{"twice", []txStep{{admin, "InitLedger", nil, ""}}, "participant CompanyA already exists"},
+		{
+			name:    "participant registered first",
+			steps:   []txStep{{admin, "RegisterParticipant", []string{"CompanyA", "Company A", "Org9MSP", "Retailer", ""}, ""}},
+			wantErr: "participant CompanyA already exists",
+		},
+		{
+			name: "product created first",
+			steps: []txStep{
+				{admin, "RegisterParticipant", []string{"CompanyC", "Company C", "Org3MSP", "Retailer", ""}, ""},
+				{org3, "CreateProduct", []string{"p1", "Widget", "CompanyC", "", "Electronics"}, ""},
+			},
+			wantErr: "product p1 already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newEmptyLedger(t)
+			l.run(tt.steps)
+
+			_, err := l.ledger.Submit(admin, "InitLedger")
+			expectError(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			if owner := l.product("p1").Owner; owner != "CompanyA" {
+				t.Errorf("p1 owner = %s, want CompanyA", owner)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const balanceObjectType = "Balance"
+
+// TokenAccount holds the token balance of an organization. Accounts are keyed
+// by MSP ID, not by participant ID: a participant's tokens are those of its
+// organization's account, and escrows, which name participants, resolve them
+// to their MSP IDs when debiting and crediting.
+type TokenAccount struct {
+	Org     string `json:"org"`
+	Balance int64  `json:"balance"`
+}
+
+// MintTokens credits amount new tokens to the account of org, which must be
+// the MSP ID of a registered participant.
+func (s *SupplyChainContract) MintTokens(ctx contractapi.TransactionContextInterface, org string, amount int64) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return fmt.Errorf("mint amount must be positive, got %d", amount)
+	}
+	if err := s.requireParticipantMSP(ctx, org); err != nil {
+		return err
+	}
+
+	account, err := s.GetBalance(ctx, org)
+	if err != nil {
+		return err
+	}
+	account.Balance += amount
+
+	err = s.putTokenAccount(ctx, account)
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %v", err)
+	}
+
+	return nil
+}
+
+// TransferTokens moves tokens from the submitter's organization to another,
+// identified by the MSP ID of a registered participant.
+func (s *SupplyChainContract) TransferTokens(ctx contractapi.TransactionContextInterface, to string, amount int64) error {
+	from, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return fmt.Errorf("cannot transfer tokens to the same organization")
+	}
+	if err := s.requireParticipantMSP(ctx, to); err != nil {
+		return err
+	}
+	if err := s.debitTokens(ctx, from, amount); err != nil {
+		return err
+	}
+	return s.creditTokens(ctx, to, amount)
+}
+
+func (s *SupplyChainContract) GetBalance(ctx contractapi.TransactionContextInterface, org string) (*TokenAccount, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(balanceObjectType, []string{org})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	accountJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read balance from ledger: %v", err)
+	}
+	if accountJSON == nil {
+		return &TokenAccount{Org: org}, nil
+	}
+
+	var account TokenAccount
+	err = json.Unmarshal(accountJSON, &account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal balance JSON: %v", err)
+	}
+
+	return &account, nil
+}
+
+func (s *SupplyChainContract) debitTokens(ctx contractapi.TransactionContextInterface, org string, amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive, got %d", amount)
+	}
+
+	account, err := s.GetBalance(ctx, org)
+	if err != nil {
+		return err
+	}
+	if account.Balance < amount {
+		return fmt.Errorf("insufficient balance for %s: have %d, need %d", org, account.Balance, amount)
+	}
+	account.Balance -= amount
+
+	err = s.putTokenAccount(ctx, account)
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) creditTokens(ctx contractapi.TransactionContextInterface, org string, amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive, got %d", amount)
+	}
+
+	account, err := s.GetBalance(ctx, org)
+	if err != nil {
+		return err
+	}
+	account.Balance += amount
+
+	err = s.putTokenAccount(ctx, account)
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) putTokenAccount(ctx contractapi.TransactionContextInterface, account *TokenAccount) error {
+	key, err := ctx.GetStub().CreateCompositeKey(balanceObjectType, []string{account.Org})
+	if err != nil {
+		return err
+	}
+
+	accountJSON, err := marshalState(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, accountJSON)
+}
@@ -0,0 +1,44 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+func TestTokenTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		function  string
+		org       string
+		wantErr   string
+	}{
+		{"mint to a participant's MSP", admin, "MintTokens", "Org2MSP", ""},
+		{"mint to an unknown MSP", admin, "MintTokens", "Org3MSP", "Org3MSP is not the MSP ID of a registered participant"},
+		{"mint to a participant ID", admin, "MintTokens", "CompanyB", "CompanyB is not the MSP ID of a registered participant"},
+		{"transfer to a participant's MSP", org1, "TransferTokens", "Org2MSP", ""},
+		{"transfer to an unknown MSP", org1, "TransferTokens", "Org3MSP", "Org3MSP is not the MSP ID of a registered participant"},
+		{"transfer to a participant ID", org1, "TransferTokens", "CompanyB", "CompanyB is not the MSP ID of a registered participant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(admin, "MintTokens", "Org1MSP", "1000")
+
+			_, err := l.ledger.Submit(tt.submitter, tt.function, tt.org, "100")
+			expectError(t, err, tt.wantErr)
+
+			var account TokenAccount
+			l.evaluate(org1, &account, "GetBalance", tt.org)
+			want := int64(0)
+			if tt.wantErr == "" {
+				want = 100
+			}
+			if account.Balance != want {
+				t.Errorf("%s balance = %d, want %d", tt.org, account.Balance, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package smartcontract
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Views, from least to most privileged. Auditors are submitters holding the
+// auditor role; owners are submitters from the owning participant's
+// organization; partners are submitters from any other active participant's
+// organization; everyone else is public.
+const (
+	viewPublic  = "public"
+	viewPartner = "partner"
+	viewOwner   = "owner"
+	viewAuditor = "auditor"
+)
+
+type ProductHistoryEntry struct {
+	TxID      string    `json:"tx_id,omitempty" metadata:",optional"`
+	Timestamp time.Time `json:"timestamp"`
+	IsDelete  bool      `json:"is_delete"`
+	Product   *Product  `json:"product,omitempty" metadata:",optional"`
+}
+
+// viewer resolves the view the submitter gets of each product. It is built
+// once per transaction so that listing queries read the participant registry
+// only once.
+type viewer struct {
+	mspID    string
+	auditor  bool
+	partner  bool
+	ownerMSP map[string]string
+}
+
+func (s *SupplyChainContract) newViewer(ctx contractapi.TransactionContextInterface) (*viewer, error) {
+	mspID, err := s.getSubmitterOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auditor, err := s.hasRole(ctx, "auditor")
+	if err != nil {
+		return nil, err
+	}
+
+	v := &viewer{mspID: mspID, auditor: auditor, ownerMSP: make(map[string]string)}
+	if auditor {
+		return v, nil
+	}
+
+	participants, err := s.GetAllParticipants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, participant := range participants {
+		v.ownerMSP[participant.ID] = participant.MSPID
+		if participant.MSPID == mspID && participant.Status == "Active" {
+			v.partner = true
+		}
+	}
+
+	return v, nil
+}
+
+// viewOf returns the submitter's view of a product held by owner.
+func (v *viewer) viewOf(owner string) string {
+	switch {
+	case v.auditor:
+		return viewAuditor
+	case v.ownerMSP[owner] != "" && v.ownerMSP[owner] == v.mspID:
+		return viewOwner
+	case v.partner:
+		return viewPartner
+	}
+	return viewPublic
+}
+
+func (v *viewer) project(product *Product) *Product {
+	return projectProduct(product, v.viewOf(product.Owner))
+}
+
+// projectProduct returns the fields of a product visible in a view. Owners
+// and auditors see everything; partners see everything but the category
+// attributes, which carry commercial detail; the public sees only what is
+// printed on the product itself.
+func projectProduct(product *Product, view string) *Product {
+	switch view {
+	case viewOwner, viewAuditor:
+		return product
+	case viewPartner:
+		projected := *product
+		projected.Attributes = nil
+		return &projected
+	}
+
+	return &Product{
+		ID:          product.ID,
+		Name:        product.Name,
+		Status:      product.Status,
+		Category:    product.Category,
+		Description: product.Description,
+		GTIN:        product.GTIN,
+	}
+}
+
+// GetProductHistory returns every version of a product in chronological
+// order. Each version is projected for the submitter as of its owner at the
+// time, so a current owner sees earlier owners' versions as a partner would.
+// Public callers see only the versions in which a publicly visible field
+// changed, without transaction IDs.
+func (s *SupplyChainContract) GetProductHistory(ctx contractapi.TransactionContextInterface, id string) ([]*ProductHistoryEntry, error) {
+	viewer, err := s.newViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	type version struct {
+		entry   *ProductHistoryEntry
+		at      time.Time
+		product *Product
+	}
+	var versions []version
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		v := version{entry: &ProductHistoryEntry{TxID: modification.TxId, IsDelete: modification.IsDelete}}
+		if modification.Timestamp != nil {
+			v.at = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
+		v.entry.Timestamp = v.at
+		if !modification.IsDelete {
+			past, err := unmarshalProduct(modification.Value)
+			if err != nil {
+				return nil, err
+			}
+			v.product = past
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("the product with ID %s does not exist", id)
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].at.Before(versions[j].at) })
+
+	history := []*ProductHistoryEntry{}
+	var previous *Product
+	for _, v := range versions {
+		if v.product == nil {
+			if viewer.viewOf("") == viewPublic {
+				v.entry.TxID = ""
+			}
+			history = append(history, v.entry)
+			previous = nil
+			continue
+		}
+
+		view := viewer.viewOf(v.product.Owner)
+		v.entry.Product = projectProduct(v.product, view)
+		if view == viewPublic {
+			v.entry.TxID = ""
+			if previous != nil && previous.Status == v.product.Status && previous.Name == v.product.Name &&
+				previous.Category == v.product.Category && previous.Description == v.product.Description && previous.GTIN == v.product.GTIN {
+				continue
+			}
+			previous = v.entry.Product
+		}
+		history = append(history, v.entry)
+	}
+
+	return history, nil
+}
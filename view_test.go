@@ -0,0 +1,113 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+// newWineLedger adds product p3, a wine owned by CompanyA with a vintage
+// attribute.
+func newWineLedger(t *testing.T) *testLedger {
+	t.Helper()
+	l := newTestLedger(t)
+	l.submit(admin, "RegisterCategory", "Wine", "Wines", wineSchema)
+	l.submit(org1, "CreateProductWithAttributes", "p3", "Bottle", "CompanyA", "Rioja", "Wine", `{"vintage":"2015"}`)
+	return l
+}
+
+func TestProductViews(t *testing.T) {
+	tests := []struct {
+		name           string
+		submitter      mockledger.Identity
+		steps          []txStep
+		wantOwner      string
+		wantAttributes bool
+	}{
+		{"auditor", auditor, nil, "CompanyA", true},
+		{"owner", org1, nil, "CompanyA", true},
+		{"partner", org2, nil, "CompanyA", false},
+		{"public", org3, nil, "", false},
+		{"suspended partner", org2, []txStep{{admin, "SuspendParticipant", []string{"CompanyB"}, ""}}, "", false},
+		{"former owner", org1, []txStep{{org1, "TransferOwnership", []string{"p3", "CompanyB"}, ""}}, "CompanyB", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newWineLedger(t)
+			l.run(tt.steps)
+
+			queried := &Product{}
+			l.evaluate(tt.submitter, queried, "QueryProduct", "p3")
+			var listed []*Product
+			l.evaluate(tt.submitter, &listed, "GetAllProducts")
+			var paged PaginatedProducts
+			l.evaluate(tt.submitter, &paged, "GetProductsWithPagination", "10", "")
+
+			products := map[string]*Product{"QueryProduct": queried}
+			for _, product := range listed {
+				if product.ID == "p3" {
+					products["GetAllProducts"] = product
+				}
+			}
+			for _, product := range paged.Records {
+				if product.ID == "p3" {
+					products["GetProductsWithPagination"] = product
+				}
+			}
+
+			for function, product := range products {
+				if product.Name != "Bottle" || product.Description != "Rioja" || product.Category != "Wine" {
+					t.Errorf("%s = %+v, want the public fields", function, product)
+				}
+				if product.Owner != tt.wantOwner {
+					t.Errorf("%s owner = %q, want %q", function, product.Owner, tt.wantOwner)
+				}
+				if hasAttributes := product.Attributes["vintage"] != ""; hasAttributes != tt.wantAttributes {
+					t.Errorf("%s attributes = %v, want visible %t", function, product.Attributes, tt.wantAttributes)
+				}
+			}
+			if len(products) != 3 {
+				t.Errorf("p3 is missing from a listing: %v", products)
+			}
+		})
+	}
+}
+
+func TestProductHistoryViews(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitter mockledger.Identity
+		wantOwner []string
+		wantTxIDs bool
+	}{
+		{"auditor", auditor, []string{"CompanyA", "CompanyA", "CompanyB", "CompanyB"}, true},
+		{"current owner", org2, []string{"CompanyA", "CompanyA", "CompanyB", "CompanyB"}, true},
+		// The public sees only creation and the status change, since
+		// neither the attributes nor the owner are visible to it.
+		{"public", org3, []string{"", ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newWineLedger(t)
+			l.submit(org1, "SetProductAttributes", "p3", `{"vintage":"2016"}`)
+			l.submit(org1, "TransferOwnership", "p3", "CompanyB")
+			l.submit(org2, "UpdateProduct", "p3", "Shipped", "CompanyB", "Rioja", "Wine")
+
+			var history []*ProductHistoryEntry
+			l.evaluate(tt.submitter, &history, "GetProductHistory", "p3")
+			if len(history) != len(tt.wantOwner) {
+				t.Fatalf("history has %d versions, want %d", len(history), len(tt.wantOwner))
+			}
+			for i, entry := range history {
+				if entry.Product.Owner != tt.wantOwner[i] {
+					t.Errorf("version %d owner = %q, want %q", i, entry.Product.Owner, tt.wantOwner[i])
+				}
+				if (entry.TxID != "") != tt.wantTxIDs {
+					t.Errorf("version %d transaction ID = %q, want shown %t", i, entry.TxID, tt.wantTxIDs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package smartcontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	warrantyTermsObjectType = "WarrantyTerms"
+	warrantyObjectType      = "Warranty"
+	warrantyClaimObjectType = "WarrantyClaim"
+)
+
+type WarrantyTerms struct {
+	Scope        string    `json:"scope"`
+	Target       string    `json:"target"`
+	Manufacturer string    `json:"manufacturer"`
+	DurationDays int       `json:"duration_days"`
+	Coverage     string    `json:"coverage"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type Warranty struct {
+	ProductID    string    `json:"product_id"`
+	Manufacturer string    `json:"manufacturer"`
+	Coverage     string    `json:"coverage"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+type WarrantyClaim struct {
+	ID          string    `json:"id"`
+	ProductID   string    `json:"product_id"`
+	Claimant    string    `json:"claimant"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	Notes       string    `json:"notes,omitempty" metadata:",optional"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SetWarrantyTerms defines the warranty for a single product (scope
+// "product") or for every product in a category (scope "category").
+// Product terms take precedence over category terms. Terms are set by the
+// manufacturer or by an admin on its behalf. Product terms can only be set
+// by the product's original owner before it is first transferred; category
+// terms are kept per manufacturer and apply to the products it sells.
+func (s *SupplyChainContract) SetWarrantyTerms(ctx contractapi.TransactionContextInterface, scope, target, manufacturer string, durationDays int, coverage string) error {
+	if scope != "product" && scope != "category" {
+		return fmt.Errorf("scope must be product or category, got %q", scope)
+	}
+	if durationDays <= 0 {
+		return fmt.Errorf("warranty duration must be positive, got %d days", durationDays)
+	}
+
+	if err := s.requireActiveParticipant(ctx, manufacturer); err != nil {
+		return err
+	}
+	isAdmin, err := s.hasRole(ctx, "admin")
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		if err := s.requireSubmitterActsFor(ctx, manufacturer, "manufacturer"); err != nil {
+			return err
+		}
+	}
+
+	attributes := []string{scope, target, manufacturer}
+	if scope == "product" {
+		product, err := s.readProduct(ctx, target)
+		if err != nil {
+			return err
+		}
+		transfer, err := s.queryTransfer(ctx, target)
+		if err != nil {
+			return err
+		}
+		if transfer != nil {
+			return fmt.Errorf("product %s has already been transferred and its warranty terms can no longer be set", target)
+		}
+		if product.Owner != manufacturer {
+			return fmt.Errorf("product %s was made by %s, not %s", target, product.Owner, manufacturer)
+		}
+		attributes = []string{scope, target}
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	terms := WarrantyTerms{
+		Scope:        scope,
+		Target:       target,
+		Manufacturer: manufacturer,
+		DurationDays: durationDays,
+		Coverage:     coverage,
+		UpdatedAt:    txTime,
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(warrantyTermsObjectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	termsJSON, err := marshalState(terms)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(key, termsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put warranty terms into ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) QueryWarranty(ctx contractapi.TransactionContextInterface, productID string) (*Warranty, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(warrantyObjectType, []string{productID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	warrantyJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read warranty from ledger: %v", err)
+	}
+	if warrantyJSON == nil {
+		return nil, fmt.Errorf("product %s has no registered warranty", productID)
+	}
+
+	var warranty Warranty
+	err = json.Unmarshal(warrantyJSON, &warranty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal warranty JSON: %v", err)
+	}
+
+	return &warranty, nil
+}
+
+// SubmitWarrantyClaim files a claim on behalf of the product's current owner,
+// who submits it. Claims made outside the coverage window are rejected.
+func (s *SupplyChainContract) SubmitWarrantyClaim(ctx contractapi.TransactionContextInterface, id, productID, description string) error {
+	product, err := s.readProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireSubmitterActsFor(ctx, product.Owner, "owner"); err != nil {
+		return err
+	}
+	if err := s.requireNotFrozen(ctx, product); err != nil {
+		return err
+	}
+
+	warranty, err := s.QueryWarranty(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if txTime.Before(warranty.StartsAt) || txTime.After(warranty.EndsAt) {
+		return fmt.Errorf("claim is outside the coverage window %s to %s for product %s", warranty.StartsAt.Format(time.RFC3339), warranty.EndsAt.Format(time.RFC3339), productID)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(warrantyClaimObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	claimJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if claimJSON != nil {
+		return fmt.Errorf("warranty claim with ID %s already exists", id)
+	}
+
+	claim := WarrantyClaim{
+		ID:          id,
+		ProductID:   productID,
+		Claimant:    product.Owner,
+		Description: description,
+		Status:      "Submitted",
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
+	}
+
+	err = s.putWarrantyClaim(ctx, &claim)
+	if err != nil {
+		return fmt.Errorf("failed to put warranty claim into ledger: %v", err)
+	}
+
+	return nil
+}
+
+// AdjudicateWarrantyClaim approves or rejects a submitted claim. Only the
+// manufacturer named in the warranty may adjudicate.
+func (s *SupplyChainContract) AdjudicateWarrantyClaim(ctx contractapi.TransactionContextInterface, id string, approved bool, notes string) error {
+	claim, err := s.QueryWarrantyClaim(ctx, id)
+	if err != nil {
+		return err
+	}
+	if claim.Status != "Submitted" {
+		return fmt.Errorf("warranty claim %s has already been %s", id, claim.Status)
+	}
+
+	warranty, err := s.QueryWarranty(ctx, claim.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.requireSubmitterActsFor(ctx, warranty.Manufacturer, "manufacturer"); err != nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	claim.Status = "Rejected"
+	if approved {
+		claim.Status = "Approved"
+	}
+	claim.Notes = notes
+	claim.UpdatedAt = txTime
+
+	err = s.putWarrantyClaim(ctx, claim)
+	if err != nil {
+		return fmt.Errorf("failed to update warranty claim: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SupplyChainContract) QueryWarrantyClaim(ctx contractapi.TransactionContextInterface, id string) (*WarrantyClaim, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(warrantyClaimObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	claimJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read warranty claim from ledger: %v", err)
+	}
+	if claimJSON == nil {
+		return nil, fmt.Errorf("the warranty claim with ID %s does not exist", id)
+	}
+
+	var claim WarrantyClaim
+	err = json.Unmarshal(claimJSON, &claim)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal warranty claim JSON: %v", err)
+	}
+
+	return &claim, nil
+}
+
+func (s *SupplyChainContract) GetWarrantyClaims(ctx contractapi.TransactionContextInterface, productID string) ([]*WarrantyClaim, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(warrantyClaimObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var claims []*WarrantyClaim
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var claim WarrantyClaim
+		if err := json.Unmarshal(queryResponse.Value, &claim); err != nil {
+			return nil, err
+		}
+		if claim.ProductID == productID {
+			claims = append(claims, &claim)
+		}
+	}
+
+	return claims, nil
+}
+
+// startWarranty registers the warranty for a product on its first sale by
+// seller. It is a no-op if the product already has a warranty or no terms
+// apply to it.
+func (s *SupplyChainContract) startWarranty(ctx contractapi.TransactionContextInterface, product *Product, seller string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(warrantyObjectType, []string{product.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	warrantyJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if warrantyJSON != nil {
+		return nil
+	}
+
+	terms, err := s.warrantyTermsFor(ctx, product, seller)
+	if err != nil || terms == nil {
+		return err
+	}
+
+	txTime, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	warranty := Warranty{
+		ProductID:    product.ID,
+		Manufacturer: terms.Manufacturer,
+		Coverage:     terms.Coverage,
+		StartsAt:     txTime,
+		EndsAt:       txTime.AddDate(0, 0, terms.DurationDays),
+	}
+
+	warrantyJSON, err = marshalState(warranty)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, warrantyJSON)
+}
+
+// warrantyTermsFor returns the terms for the product, falling back to the
+// seller's terms for its category.
+func (s *SupplyChainContract) warrantyTermsFor(ctx contractapi.TransactionContextInterface, product *Product, seller string) (*WarrantyTerms, error) {
+	for _, attributes := range [][]string{{"product", product.ID}, {"category", product.Category, seller}} {
+		key, err := ctx.GetStub().CreateCompositeKey(warrantyTermsObjectType, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		termsJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read warranty terms: %v", err)
+		}
+		if termsJSON == nil {
+			continue
+		}
+
+		var terms WarrantyTerms
+		if err := json.Unmarshal(termsJSON, &terms); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal warranty terms JSON: %v", err)
+		}
+		return &terms, nil
+	}
+	return nil, nil
+}
+
+func (s *SupplyChainContract) putWarrantyClaim(ctx contractapi.TransactionContextInterface, claim *WarrantyClaim) error {
+	key, err := ctx.GetStub().CreateCompositeKey(warrantyClaimObjectType, []string{claim.ID})
+	if err != nil {
+		return err
+	}
+
+	claimJSON, err := marshalState(claim)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, claimJSON)
+}
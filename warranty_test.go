@@ -0,0 +1,136 @@
+package smartcontract
+
+import (
+	"testing"
+
+	"github.com/Joeychen80627/smartcontract/internal/mockledger"
+)
+
+func TestSetWarrantyTerms(t *testing.T) {
+	tests := []struct {
+		name         string
+		submitter    mockledger.Identity
+		manufacturer string
+		wantErr      string
+	}{
+		{"manufacturer", org2, "CompanyB", ""},
+		{"admin on behalf", admin, "CompanyB", ""},
+		{"another organization", org1, "CompanyB", "not authorized to act as manufacturer CompanyB"},
+		{"unregistered manufacturer", admin, "Nobody", "Nobody is not a registered participant"},
+		{"another organization's product", org1, "CompanyA", "product p2 was made by CompanyB, not CompanyA"},
+		{"admin for another organization's product", admin, "CompanyA", "product p2 was made by CompanyB, not CompanyA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			_, err := l.ledger.Submit(tt.submitter, "SetWarrantyTerms", "product", "p2", tt.manufacturer, "365", "parts")
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestProductWarrantyTermsFixedOnTransfer(t *testing.T) {
+	l := newSoldLedger(t)
+
+	l.run([]txStep{
+		{org1, "SetWarrantyTerms", []string{"product", "p2", "CompanyA", "3650", "everything"}, "product p2 has already been transferred"},
+		{org2, "SetWarrantyTerms", []string{"product", "p2", "CompanyB", "3650", "everything"}, "product p2 has already been transferred"},
+	})
+}
+
+func TestCategoryWarrantyTermsPerManufacturer(t *testing.T) {
+	l := newTestLedger(t)
+	l.submit(org2, "SetWarrantyTerms", "category", "Electronics", "CompanyB", "365", "parts")
+	l.submit(org1, "SetWarrantyTerms", "category", "Electronics", "CompanyA", "30", "none")
+	l.submit(org2, "TransferOwnership", "p2", "CompanyA")
+	l.submit(org1, "TransferOwnership", "p1", "CompanyB")
+
+	for productID, want := range map[string]string{"p1": "CompanyA", "p2": "CompanyB"} {
+		var warranty Warranty
+		l.evaluate(org1, &warranty, "QueryWarranty", productID)
+		if warranty.Manufacturer != want {
+			t.Errorf("%s warranty by %s, want %s", productID, warranty.Manufacturer, want)
+		}
+	}
+}
+
+func TestWarrantyStartsOnSale(t *testing.T) {
+	tests := []struct {
+		name string
+		sell txStep
+	}{
+		{"transfer", txStep{org2, "TransferOwnership", []string{"p2", "CompanyA"}, ""}},
+		{"update", txStep{org2, "UpdateProduct", []string{"p2", "Manufactured", "CompanyA", "Latest model smartphone", "Electronics"}, ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org2, "SetWarrantyTerms", "category", "Electronics", "CompanyB", "365", "parts")
+			l.run([]txStep{tt.sell})
+
+			var warranty Warranty
+			l.evaluate(org1, &warranty, "QueryWarranty", "p2")
+			if warranty.Manufacturer != "CompanyB" || warranty.StartsAt.IsZero() || !warranty.EndsAt.Equal(warranty.StartsAt.AddDate(0, 0, 365)) {
+				t.Errorf("warranty = %+v, want one by CompanyB for 365 days", warranty)
+			}
+		})
+	}
+}
+
+func TestWarrantyClaims(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []txStep
+		wantStatus string
+	}{
+		{
+			name:       "owner claims",
+			steps:      []txStep{{org1, "SubmitWarrantyClaim", []string{"c1", "p2", "cracked"}, ""}},
+			wantStatus: "Submitted",
+		},
+		{
+			name: "manufacturer approves",
+			steps: []txStep{
+				{org1, "SubmitWarrantyClaim", []string{"c1", "p2", "cracked"}, ""},
+				{org2, "AdjudicateWarrantyClaim", []string{"c1", "true", "replaced"}, ""},
+			},
+			wantStatus: "Approved",
+		},
+		{
+			name: "owner cannot adjudicate",
+			steps: []txStep{
+				{org1, "SubmitWarrantyClaim", []string{"c1", "p2", "cracked"}, ""},
+				{org1, "AdjudicateWarrantyClaim", []string{"c1", "true", ""}, "not authorized to act as manufacturer CompanyB"},
+			},
+			wantStatus: "Submitted",
+		},
+		{
+			name:  "former owner cannot claim",
+			steps: []txStep{{org2, "SubmitWarrantyClaim", []string{"c1", "p2", "cracked"}, "not authorized to act as owner CompanyA"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLedger(t)
+			l.submit(org2, "SetWarrantyTerms", "product", "p2", "CompanyB", "365", "parts")
+			l.submit(org2, "TransferOwnership", "p2", "CompanyA")
+
+			l.run(tt.steps)
+
+			var claims []*WarrantyClaim
+			l.evaluate(org1, &claims, "GetWarrantyClaims", "p2")
+			if tt.wantStatus == "" {
+				if len(claims) != 0 {
+					t.Errorf("claims = %v, want none", claims)
+				}
+				return
+			}
+			if len(claims) != 1 || claims[0].Status != tt.wantStatus || claims[0].Claimant != "CompanyA" {
+				t.Errorf("claims = %v, want one by CompanyA %s", claims, tt.wantStatus)
+			}
+		})
+	}
+}